@@ -0,0 +1,338 @@
+package ddb
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	// batchGetMaxKeys is the maximum nr of keys DynamoDB accepts in one BatchGetItem request
+	batchGetMaxKeys = 100
+
+	// batchWriteMaxItems is the maximum nr of items DynamoDB accepts in one BatchWriteItem request
+	batchWriteMaxItems = 25
+
+	// batchMaxAttempts is the nr of times a batch request is send before unprocessed
+	// items cause an error to be returned
+	batchMaxAttempts = 10
+
+	// batchBackoffBase and batchBackoffMax bound the exponential backoff between attempts
+	batchBackoffBase = 50 * time.Millisecond
+	batchBackoffMax  = 5 * time.Second
+)
+
+// batchGetGroup holds the keys that can be fetched with the same KeysAndAttributes
+type batchGetGroup struct {
+	get   *dynamodb.Get
+	table string
+	ka    dynamodb.KeysAndAttributes
+	pos   map[string][]int
+	added []string
+}
+
+// runBatch runs the reads through the BatchGetItem api. Items are returned in the order
+// in which the get operations were added to the reader, keys that do not exist are
+// omitted from the result.
+func (r *Reader) runBatch(ctx context.Context, ddb Dynamo) (res Result, err error) {
+	var groups []*batchGetGroup
+	for i, ri := range r.reads {
+		g := findBatchGetGroup(groups, ri.Get)
+		if g == nil {
+			g = newBatchGetGroup(ri.Get)
+			g.ka.ConsistentRead = r.opts.consistentRead(ctx)
+			groups = append(groups, g)
+		}
+
+		// DynamoDB doesn't allow duplicate keys in one batch so we only request it once
+		ks := keyString(ri.Get.Key)
+		if _, ok := g.pos[ks]; !ok {
+			g.ka.Keys = append(g.ka.Keys, ri.Get.Key)
+		}
+
+		g.pos[ks] = append(g.pos[ks], i)
+	}
+
+	items := make([]map[string]*dynamodb.AttributeValue, len(r.reads))
+	for _, g := range groups {
+		for i := 0; i < len(g.ka.Keys); i += batchGetMaxKeys {
+			j := i + batchGetMaxKeys
+			if j > len(g.ka.Keys) {
+				j = len(g.ka.Keys)
+			}
+
+			ka := g.ka
+			ka.Keys = g.ka.Keys[i:j]
+			if err = batchGet(ctx, ddb, g, &ka, items, r.opts.returnConsumedCapacity()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var found []map[string]*dynamodb.AttributeValue
+	for _, it := range items {
+		if it != nil {
+			found = append(found, it)
+		}
+	}
+
+	if len(found) < 1 {
+		return emptyResult{}, nil
+	}
+
+	return newResult(found...), nil
+}
+
+// batchGet will fetch one chunk of keys and retries any unprocessed keys with backoff. Each
+// returned item is placed in 'items' at the position(s) the key was requested.
+func batchGet(
+	ctx context.Context,
+	ddb Dynamo,
+	g *batchGetGroup,
+	ka *dynamodb.KeysAndAttributes,
+	items []map[string]*dynamodb.AttributeValue,
+	rcc *string,
+) (err error) {
+	in := &dynamodb.BatchGetItemInput{
+		RequestItems:           map[string]*dynamodb.KeysAndAttributes{g.table: ka},
+		ReturnConsumedCapacity: rcc,
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if err = sleepCtx(ctx, backoff(attempt, batchBackoffBase, batchBackoffMax)); err != nil {
+				return fmt.Errorf("failed to wait for batch get retry: %w", err)
+			}
+		}
+
+		var out *dynamodb.BatchGetItemOutput
+		if out, err = ddb.BatchGetItemWithContext(ctx, in); err != nil {
+			return fmt.Errorf("failed to batch get: %w", err)
+		}
+
+		addCapacity(ctx, false, out.ConsumedCapacity...)
+
+		for _, it := range out.Responses[g.table] {
+			poss := g.pos[keyString(mapFilter(it, keyNames(ka.Keys[0])...))]
+			for _, name := range g.added {
+				delete(it, name)
+			}
+
+			for _, pos := range poss {
+				items[pos] = it
+			}
+		}
+
+		uka := out.UnprocessedKeys[g.table]
+		if uka == nil || len(uka.Keys) < 1 {
+			return nil
+		}
+
+		if attempt+1 >= batchMaxAttempts {
+			return fmt.Errorf("failed to batch get %d key(s) after %d attempts", len(uka.Keys), batchMaxAttempts)
+		}
+
+		in.RequestItems = map[string]*dynamodb.KeysAndAttributes{g.table: uka}
+	}
+}
+
+// runBatch runs the writes through the BatchWriteItem api in chunks. Chunks are written in the
+// order the operations were added, writes to the same key never end up in the same chunk.
+func (tx *Writer) runBatch(ctx context.Context, ddb Dynamo) (r Result, err error) {
+	var chunk map[string][]*dynamodb.WriteRequest
+	var seen map[string]struct{}
+	var n int
+
+	flush := func() error {
+		if n < 1 {
+			return nil
+		}
+
+		if err := batchWrite(ctx, ddb, chunk, tx.opts.returnConsumedCapacity()); err != nil {
+			return err
+		}
+
+		chunk, seen, n = nil, nil, 0
+		return nil
+	}
+
+	for i, wi := range tx.writes {
+		var wr *dynamodb.WriteRequest
+		var table string
+		switch {
+		case wi.Put != nil:
+			table = aws.StringValue(wi.Put.TableName)
+			wr = &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: wi.Put.Item}}
+		case wi.Delete != nil:
+			table = aws.StringValue(wi.Delete.TableName)
+			wr = &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: wi.Delete.Key}}
+		default:
+			return nil, fmt.Errorf("unsupported batch operation: %v", wi)
+		}
+
+		ks := table + "/" + keyString(tx.keys[i])
+		if _, ok := seen[ks]; ok || n >= batchWriteMaxItems {
+			if err = flush(); err != nil {
+				return nil, err
+			}
+		}
+
+		if chunk == nil {
+			chunk, seen = map[string][]*dynamodb.WriteRequest{}, map[string]struct{}{}
+		}
+
+		chunk[table] = append(chunk[table], wr)
+		seen[ks] = struct{}{}
+		n++
+	}
+
+	if err = flush(); err != nil {
+		return nil, err
+	}
+
+	return emptyResult{}, nil
+}
+
+// batchWrite will write one chunk and retries any unprocessed items with backoff
+func batchWrite(
+	ctx context.Context,
+	ddb Dynamo,
+	chunk map[string][]*dynamodb.WriteRequest,
+	rcc *string,
+) (err error) {
+	in := &dynamodb.BatchWriteItemInput{RequestItems: chunk, ReturnConsumedCapacity: rcc}
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if err = sleepCtx(ctx, backoff(attempt, batchBackoffBase, batchBackoffMax)); err != nil {
+				return fmt.Errorf("failed to wait for batch write retry: %w", err)
+			}
+		}
+
+		var out *dynamodb.BatchWriteItemOutput
+		if out, err = ddb.BatchWriteItemWithContext(ctx, in); err != nil {
+			return fmt.Errorf("failed to batch write: %w", err)
+		}
+
+		addCapacity(ctx, true, out.ConsumedCapacity...)
+
+		var n int
+		for _, wrs := range out.UnprocessedItems {
+			n += len(wrs)
+		}
+
+		if n < 1 {
+			return nil
+		}
+
+		if attempt+1 >= batchMaxAttempts {
+			return fmt.Errorf("failed to batch write %d item(s) after %d attempts", n, batchMaxAttempts)
+		}
+
+		in.RequestItems = out.UnprocessedItems
+	}
+}
+
+// newBatchGetGroup inits a group for gets that are similar to 'get'. If the get has a projection
+// the key attributes that it lacks are added to it so returned items can be matched to the
+// requested keys, they are removed from the items again after matching.
+func newBatchGetGroup(get *dynamodb.Get) (g *batchGetGroup) {
+	g = &batchGetGroup{get: get, table: aws.StringValue(get.TableName), pos: map[string][]int{}}
+	if get.ProjectionExpression == nil {
+		return g
+	}
+
+	proj := aws.StringValue(get.ProjectionExpression)
+	g.ka.ExpressionAttributeNames = map[string]*string{}
+	for k, v := range get.ExpressionAttributeNames {
+		g.ka.ExpressionAttributeNames[k] = v
+	}
+
+	// DynamoDB refuses a projection that names an attribute twice
+	projected := map[string]bool{}
+	for _, p := range strings.Split(proj, ",") {
+		name := strings.TrimSpace(p)
+		if i := strings.IndexAny(name, ".["); i >= 0 {
+			name = name[:i]
+		}
+
+		if v, ok := get.ExpressionAttributeNames[name]; ok {
+			name = aws.StringValue(v)
+		}
+
+		projected[name] = true
+	}
+
+	for i, name := range keyNames(get.Key) {
+		if projected[name] {
+			continue
+		}
+
+		g.added = append(g.added, name)
+		ph := fmt.Sprintf("#ddbkey%d", i)
+		g.ka.ExpressionAttributeNames[ph] = aws.String(name)
+		proj += ", " + ph
+	}
+
+	g.ka.ProjectionExpression = aws.String(proj)
+	return g
+}
+
+// findBatchGetGroup returns the group that 'get' can be added to, or nil if there is none
+func findBatchGetGroup(groups []*batchGetGroup, get *dynamodb.Get) *batchGetGroup {
+	for _, g := range groups {
+		if g.table != aws.StringValue(get.TableName) ||
+			aws.StringValue(g.get.ProjectionExpression) != aws.StringValue(get.ProjectionExpression) ||
+			len(g.get.ExpressionAttributeNames) != len(get.ExpressionAttributeNames) {
+			continue
+		}
+
+		same := true
+		for k, v := range get.ExpressionAttributeNames {
+			if aws.StringValue(g.get.ExpressionAttributeNames[k]) != aws.StringValue(v) {
+				same = false
+			}
+		}
+
+		if same {
+			return g
+		}
+	}
+
+	return nil
+}
+
+// keyNames returns the attribute names of a key
+func keyNames(key map[string]*dynamodb.AttributeValue) (names []string) {
+	for name := range key {
+		names = append(names, name)
+	}
+	return
+}
+
+// backoff returns a random duration (full jitter) that grows exponentially with the attempt
+func backoff(attempt int, base, max time.Duration) time.Duration {
+	d := max
+	if attempt < 32 && base<<uint(attempt) < max && base<<uint(attempt) > 0 {
+		d = base << uint(attempt)
+	}
+
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+// sleepCtx sleeps for duration 'd' or returns an error when the context is done before that
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,177 @@
+package ddb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/gohandle/ddb/internal/ddbtest"
+)
+
+// unprocessedDynamo processes only the first key, or write, of each table in the first 'n' batch
+// requests and returns the rest as unprocessed
+type unprocessedDynamo struct {
+	Dynamo
+	n     int
+	calls int
+}
+
+func (uddb *unprocessedDynamo) BatchGetItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.BatchGetItemInput,
+	opts ...request.Option,
+) (*dynamodb.BatchGetItemOutput, error) {
+	uddb.calls++
+	if uddb.calls > uddb.n {
+		return uddb.Dynamo.BatchGetItemWithContext(ctx, in, opts...)
+	}
+
+	first := &dynamodb.BatchGetItemInput{RequestItems: map[string]*dynamodb.KeysAndAttributes{}}
+	rest := map[string]*dynamodb.KeysAndAttributes{}
+	for table, ka := range in.RequestItems {
+		f, r := *ka, *ka
+		f.Keys, r.Keys = ka.Keys[:1], ka.Keys[1:]
+		first.RequestItems[table] = &f
+		if len(r.Keys) > 0 {
+			rest[table] = &r
+		}
+	}
+
+	out, err := uddb.Dynamo.BatchGetItemWithContext(ctx, first, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	out.UnprocessedKeys = rest
+	return out, nil
+}
+
+func (uddb *unprocessedDynamo) BatchWriteItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.BatchWriteItemInput,
+	opts ...request.Option,
+) (*dynamodb.BatchWriteItemOutput, error) {
+	uddb.calls++
+	if uddb.calls > uddb.n {
+		return uddb.Dynamo.BatchWriteItemWithContext(ctx, in, opts...)
+	}
+
+	first := &dynamodb.BatchWriteItemInput{RequestItems: map[string][]*dynamodb.WriteRequest{}}
+	rest := map[string][]*dynamodb.WriteRequest{}
+	for table, wrs := range in.RequestItems {
+		first.RequestItems[table] = wrs[:1]
+		if len(wrs) > 1 {
+			rest[table] = wrs[1:]
+		}
+	}
+
+	out, err := uddb.Dynamo.BatchWriteItemWithContext(ctx, first, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	out.UnprocessedItems = rest
+	return out, nil
+}
+
+// fastBatchBackoff makes the backoff between batch attempts short for the duration of a test
+func fastBatchBackoff(t *testing.T) {
+	base, max := batchBackoffBase, batchBackoffMax
+	batchBackoffBase, batchBackoffMax = time.Microsecond, time.Millisecond
+	t.Cleanup(func() { batchBackoffBase, batchBackoffMax = base, max })
+}
+
+func TestBatchGetUnprocessed(t *testing.T) {
+	ctx, tbl := context.Background(), table1(t.Name())
+	mddb := ddbtest.MemDB(t, tbl.createInput())
+	fastBatchBackoff(t)
+
+	w := NewWriter()
+	for i := 0; i < 12; i++ {
+		w.Put(tbl.simplePut1(&table1Entity{ID: i, Name: "foo"}))
+	}
+
+	if _, err := w.Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	get := func(d Dynamo) (Result, error) {
+		rd := NewReader(EnableBatchReads())
+		for i := 0; i < 12; i++ {
+			rd.Get(tbl.simpleGet1(i))
+		}
+
+		return rd.Run(ctx, d)
+	}
+
+	uddb := &unprocessedDynamo{Dynamo: mddb, n: 3}
+	r, err := get(uddb)
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if r.Len() != 12 || uddb.calls != 4 {
+		t.Fatalf("got: %v %v", r.Len(), uddb.calls)
+	}
+
+	uddb = &unprocessedDynamo{Dynamo: mddb, n: 100}
+	if _, err = get(uddb); err == nil || !strings.Contains(err.Error(), "after 10 attempts") {
+		t.Fatalf("got: %v", err)
+	}
+
+	if uddb.calls != batchMaxAttempts {
+		t.Fatalf("got: %v", uddb.calls)
+	}
+}
+
+func TestBatchWriteUnprocessed(t *testing.T) {
+	ctx, tbl := context.Background(), table1(t.Name())
+	mddb := ddbtest.MemDB(t, tbl.createInput())
+	fastBatchBackoff(t)
+
+	put := func(d Dynamo, name string) error {
+		w := NewWriter(EnableBatchWrites())
+		for i := 0; i < 12; i++ {
+			w.Put(tbl.simplePut1(&table1Entity{ID: i, Name: name}))
+		}
+
+		_, err := w.Run(ctx, d)
+		return err
+	}
+
+	uddb := &unprocessedDynamo{Dynamo: mddb, n: 3}
+	if err := put(uddb, "foo"); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if uddb.calls != 4 {
+		t.Fatalf("got: %v", uddb.calls)
+	}
+
+	r, err := Scan(tbl.simpleScan()).Run(ctx, mddb)
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	var n int
+	for r.Next() {
+		n++
+	}
+
+	if n != 12 {
+		t.Fatalf("got: %v", n)
+	}
+
+	uddb = &unprocessedDynamo{Dynamo: mddb, n: 100}
+	if err = put(uddb, "bar"); err == nil || !strings.Contains(err.Error(), "after 10 attempts") {
+		t.Fatalf("got: %v", err)
+	}
+
+	if uddb.calls != batchMaxAttempts {
+		t.Fatalf("got: %v", uddb.calls)
+	}
+}
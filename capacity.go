@@ -0,0 +1,149 @@
+package ddb
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// CapacityUnits holds an amount of consumed read and write capacity units
+type CapacityUnits struct {
+	Read  float64
+	Write float64
+}
+
+// Total returns the sum of the read and write capacity units
+func (u CapacityUnits) Total() float64 { return u.Read + u.Write }
+
+// add returns the sum of both units
+func (u CapacityUnits) add(o CapacityUnits) CapacityUnits {
+	return CapacityUnits{Read: u.Read + o.Read, Write: u.Write + o.Write}
+}
+
+// capacityUnits reads the units from a capacity. If DynamoDB didn't split them up into read and
+// write units, all units are attributed to the kind of operation that consumed them.
+func capacityUnits(total, read, write *float64, isWrite bool) CapacityUnits {
+	switch {
+	case read != nil || write != nil:
+		return CapacityUnits{Read: aws.Float64Value(read), Write: aws.Float64Value(write)}
+	case isWrite:
+		return CapacityUnits{Write: aws.Float64Value(total)}
+	default:
+		return CapacityUnits{Read: aws.Float64Value(total)}
+	}
+}
+
+// Capacity accumulates the capacity that is consumed by operations, in total, per table and per
+// index. It is safe for concurrent use.
+type Capacity struct {
+	mu      sync.Mutex
+	total   CapacityUnits
+	tables  map[string]CapacityUnits
+	indexes map[string]map[string]CapacityUnits
+}
+
+// capacityKey is the context key that holds the capacity accumulator
+type capacityKey struct{}
+
+// WithCapacity returns a context with a new capacity accumulator. The capacity that is returned
+// to operations that run with the context, or a context derived from it, is added to it. Only
+// operations that ask for the consumed capacity (see EnableConsumedCapacity) have it returned.
+func WithCapacity(ctx context.Context) (context.Context, *Capacity) {
+	c := &Capacity{
+		tables:  map[string]CapacityUnits{},
+		indexes: map[string]map[string]CapacityUnits{},
+	}
+
+	return context.WithValue(ctx, capacityKey{}, c), c
+}
+
+// CapacityFromContext returns the capacity accumulator of the context, or nil if it has none
+func CapacityFromContext(ctx context.Context) *Capacity {
+	c, _ := ctx.Value(capacityKey{}).(*Capacity)
+	return c
+}
+
+// addCapacity adds consumed capacity to the accumulator in the context, if there is one
+func addCapacity(ctx context.Context, isWrite bool, ccs ...*dynamodb.ConsumedCapacity) {
+	if c := CapacityFromContext(ctx); c != nil {
+		c.Add(isWrite, ccs...)
+	}
+}
+
+// Add consumed capacity as returned by DynamoDB. If the capacity isn't split up into read and
+// write units it is attributed to writes if isWrite is true, and reads otherwise.
+func (c *Capacity) Add(isWrite bool, ccs ...*dynamodb.ConsumedCapacity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, cc := range ccs {
+		if cc == nil {
+			continue
+		}
+
+		table := aws.StringValue(cc.TableName)
+		u := capacityUnits(cc.CapacityUnits, cc.ReadCapacityUnits, cc.WriteCapacityUnits, isWrite)
+		c.total = c.total.add(u)
+		c.tables[table] = c.tables[table].add(u)
+
+		for _, idxs := range []map[string]*dynamodb.Capacity{cc.GlobalSecondaryIndexes, cc.LocalSecondaryIndexes} {
+			for name, ic := range idxs {
+				if c.indexes[table] == nil {
+					c.indexes[table] = map[string]CapacityUnits{}
+				}
+
+				iu := capacityUnits(ic.CapacityUnits, ic.ReadCapacityUnits, ic.WriteCapacityUnits, isWrite)
+				c.indexes[table][name] = c.indexes[table][name].add(iu)
+			}
+		}
+	}
+}
+
+// Total returns all capacity that was consumed
+func (c *Capacity) Total() CapacityUnits {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.total
+}
+
+// Table returns the capacity that was consumed by operations on the table, including what was
+// consumed by its indexes.
+func (c *Capacity) Table(name string) CapacityUnits {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tables[name]
+}
+
+// Index returns the capacity that was consumed by an index of a table
+func (c *Capacity) Index(table, name string) CapacityUnits {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.indexes[table][name]
+}
+
+// Tables returns the sorted names of the tables that consumed capacity
+func (c *Capacity) Tables() (names []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name := range c.tables {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return
+}
+
+// Indexes returns the sorted names of the indexes of a table that consumed capacity
+func (c *Capacity) Indexes(table string) (names []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name := range c.indexes[table] {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return
+}
@@ -0,0 +1,89 @@
+package ddb
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/gohandle/ddb/internal/ddbtest"
+)
+
+func TestCapacityAdd(t *testing.T) {
+	_, c := WithCapacity(context.Background())
+	c.Add(true, &dynamodb.ConsumedCapacity{TableName: aws.String("a"), CapacityUnits: aws.Float64(2)}, nil)
+	c.Add(false, &dynamodb.ConsumedCapacity{
+		TableName:          aws.String("b"),
+		CapacityUnits:      aws.Float64(3),
+		ReadCapacityUnits:  aws.Float64(1),
+		WriteCapacityUnits: aws.Float64(2),
+		LocalSecondaryIndexes: map[string]*dynamodb.Capacity{
+			"lsi1": {CapacityUnits: aws.Float64(1)},
+		},
+	})
+
+	if act := c.Total(); act != (CapacityUnits{Read: 1, Write: 4}) || act.Total() != 5 {
+		t.Fatalf("got: %v", act)
+	}
+
+	if act := c.Table("a"); act != (CapacityUnits{Write: 2}) {
+		t.Fatalf("got: %v", act)
+	}
+
+	if act := c.Index("b", "lsi1"); act != (CapacityUnits{Read: 1}) {
+		t.Fatalf("got: %v", act)
+	}
+
+	if act := c.Tables(); !reflect.DeepEqual(act, []string{"a", "b"}) {
+		t.Fatalf("got: %v", act)
+	}
+}
+
+func TestConsumedCapacity(t *testing.T) {
+	tbl := table2(t.Name())
+	mddb := ddbtest.MemDB(t, tbl.createInput())
+
+	ctx, c := WithCapacity(context.Background())
+	if _, err := NewWriter(EnableConsumedCapacity()).
+		Put(tbl.Put1(&table2Entity{ID: 1, Kind: 1})).
+		Put(tbl.Put1(&table2Entity{ID: 2, Kind: 1})).
+		Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if _, err := NewWriter(EnableConsumedCapacity()).Put(tbl.Put1(&table2Entity{ID: 3, Kind: 1})).Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if _, err := NewReader(EnableConsumedCapacity()).Get(tbl.Get1(1)).Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	// the query has a limit of 2, so it reads two pages
+	b, q := tbl.ByKind(1)
+	r, err := Query(b, q, EnableConsumedCapacity()).Run(ctx, mddb)
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	for r.Next() {
+	}
+
+	// without the option nothing is returned, and nothing is added
+	if _, err := NewReader().Get(tbl.Get1(1)).Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if act := c.Total(); act != (CapacityUnits{Read: 1.5, Write: 10}) {
+		t.Fatalf("got: %v", act)
+	}
+
+	if act := c.Table(string(tbl)); act != (CapacityUnits{Read: 1.5, Write: 10}) {
+		t.Fatalf("got: %v", act)
+	}
+
+	if act := c.Index(string(tbl), "gsi1"); act != (CapacityUnits{Read: 1, Write: 5}) {
+		t.Fatalf("got: %v", act)
+	}
+}
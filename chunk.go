@@ -0,0 +1,176 @@
+package ddb
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	// txMaxOperations is the maximum nr of operations DynamoDB accepts in one transaction
+	txMaxOperations = 100
+
+	// txMaxSize is the maximum aggregate size of the items in one transaction
+	txMaxSize = 4 * 1024 * 1024
+)
+
+// chunkWrites splits the writes into ordered chunks that each fit in one transaction. It returns
+// the nr of writes in each chunk or a limit error if a single write doesn't fit by itself.
+func chunkWrites(writes []*dynamodb.TransactWriteItem) (chunks []int, err error) {
+	var n, size int
+	for _, wi := range writes {
+		ws := writeSize(wi)
+		if ws > txMaxSize {
+			return nil, &TxLimitError{Limit: TxLimitSize, Max: txMaxSize, Actual: ws}
+		}
+
+		if n > 0 && (n >= txMaxOperations || size+ws > txMaxSize) {
+			chunks = append(chunks, n)
+			n, size = 0, 0
+		}
+
+		n++
+		size += ws
+	}
+
+	if n > 0 {
+		chunks = append(chunks, n)
+	}
+
+	return
+}
+
+// checkWriteLimits returns a limit error if the writes do not fit in a single transaction
+func checkWriteLimits(writes []*dynamodb.TransactWriteItem) error {
+	if len(writes) > txMaxOperations {
+		return &TxLimitError{Limit: TxLimitOperations, Max: txMaxOperations, Actual: len(writes)}
+	}
+
+	var size int
+	for _, wi := range writes {
+		size += writeSize(wi)
+	}
+
+	if size > txMaxSize {
+		return &TxLimitError{Limit: TxLimitSize, Max: txMaxSize, Actual: size}
+	}
+
+	return nil
+}
+
+// runChunked runs the writes as several transactions, one after the other. This is not atomic:
+// if a chunk fails the chunks before it stay committed.
+func (tx *Writer) runChunked(ctx context.Context, ddb Dynamo) (r Result, err error) {
+	chunks, err := chunkWrites(tx.writes)
+	if err != nil {
+		return nil, err
+	}
+
+	var offs int
+	for i, n := range chunks {
+		if err = tx.transact(ctx, ddb, tx.writes[offs:offs+n], offs); err != nil {
+			return nil, &TxChunkError{Chunks: chunks, Failed: i, Err: err}
+		}
+
+		offs += n
+	}
+
+	return emptyResult{}, nil
+}
+
+// writeSize estimates the nr of bytes a write operation contributes to the transaction size
+func writeSize(wi *dynamodb.TransactWriteItem) (n int) {
+	var item map[string]*dynamodb.AttributeValue
+	var vals map[string]*dynamodb.AttributeValue
+	var names map[string]*string
+	var exprs []*string
+	switch {
+	case wi.Put != nil:
+		item, vals, names = wi.Put.Item, wi.Put.ExpressionAttributeValues, wi.Put.ExpressionAttributeNames
+		exprs = []*string{wi.Put.ConditionExpression}
+	case wi.Update != nil:
+		item, vals, names = wi.Update.Key, wi.Update.ExpressionAttributeValues, wi.Update.ExpressionAttributeNames
+		exprs = []*string{wi.Update.ConditionExpression, wi.Update.UpdateExpression}
+	case wi.Delete != nil:
+		item, vals, names = wi.Delete.Key, wi.Delete.ExpressionAttributeValues, wi.Delete.ExpressionAttributeNames
+		exprs = []*string{wi.Delete.ConditionExpression}
+	case wi.ConditionCheck != nil:
+		item, vals = wi.ConditionCheck.Key, wi.ConditionCheck.ExpressionAttributeValues
+		names = wi.ConditionCheck.ExpressionAttributeNames
+		exprs = []*string{wi.ConditionCheck.ConditionExpression}
+	}
+
+	n = itemSize(item) + itemSize(vals)
+	for k, v := range names {
+		n += len(k) + len(aws.StringValue(v))
+	}
+
+	for _, e := range exprs {
+		n += len(aws.StringValue(e))
+	}
+
+	return
+}
+
+// itemSize returns the size of an item following the rules DynamoDB uses to calculate it
+func itemSize(item map[string]*dynamodb.AttributeValue) (n int) {
+	for name, av := range item {
+		n += len(name) + attrSize(av)
+	}
+	return
+}
+
+// attrSize returns the size of a single attribute value
+func attrSize(av *dynamodb.AttributeValue) (n int) {
+	switch {
+	case av == nil:
+		return 0
+	case av.S != nil:
+		return len(*av.S)
+	case av.N != nil:
+		return numSize(*av.N)
+	case av.B != nil:
+		return len(av.B)
+	case av.BOOL != nil, av.NULL != nil:
+		return 1
+	case av.SS != nil:
+		for _, s := range av.SS {
+			n += len(aws.StringValue(s))
+		}
+		return
+	case av.NS != nil:
+		for _, s := range av.NS {
+			n += numSize(aws.StringValue(s))
+		}
+		return
+	case av.BS != nil:
+		for _, b := range av.BS {
+			n += len(b)
+		}
+		return
+	case av.L != nil:
+		n = 3
+		for _, v := range av.L {
+			n += 1 + attrSize(v)
+		}
+		return
+	case av.M != nil:
+		return 3 + len(av.M) + itemSize(av.M)
+	}
+
+	return 0
+}
+
+// numSize approximates the size of a number: roughly one byte per two significant digits
+// plus one byte.
+func numSize(s string) int {
+	var digits int
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+
+	return (digits+1)/2 + 1
+}
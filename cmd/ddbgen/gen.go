@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// placeholder matches the field references in a key template, for example: "USER#{ID}"
+var placeholder = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// key describes how a key attribute is formatted from the fields of an entity
+type key struct {
+	Attr   string
+	Expr   string // go expression that formats the key from entity 'e'
+	Sample string // the key formatted from the sample values, empty if it can't be predicted
+}
+
+// field is an exported field of the entity that is stored as an item attribute
+type field struct {
+	Name   string
+	Type   string
+	Tag    string
+	Sample string // go literal used by the round-trip test, empty for the zero value
+}
+
+// entity describes a struct type that the item and mapping methods are generated for
+type entity struct {
+	Name   string
+	PK, SK *key
+	Fields []field
+}
+
+// source holds the parsed files of the package that is generated for
+type source struct {
+	fset  *token.FileSet
+	pkg   string
+	specs map[string]*ast.TypeSpec
+	files map[string]*ast.File
+}
+
+// parseDir parses the non-test go files of a package directory
+func parseDir(dir string) (src *source, err error) {
+	src = &source{fset: token.NewFileSet(), specs: map[string]*ast.TypeSpec{}, files: map[string]*ast.File{}}
+	pkgs, err := parser.ParseDir(src.fset, dir, func(fi fs.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse package: %w", err)
+	}
+
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected one package in '%s', got: %d", dir, len(pkgs))
+	}
+
+	for name, pkg := range pkgs {
+		src.pkg = name
+		for _, f := range pkg.Files {
+			for _, decl := range f.Decls {
+				gd, ok := decl.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+
+				for _, spec := range gd.Specs {
+					ts := spec.(*ast.TypeSpec)
+					src.specs[ts.Name.Name] = ts
+					src.files[ts.Name.Name] = f
+				}
+			}
+		}
+	}
+
+	return src, nil
+}
+
+// entity reads the struct type with the provided name, its ddb tag and its fields
+func (src *source) entity(name string) (ent *entity, imports []string, err error) {
+	ts, ok := src.specs[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("type '%s' not found", name)
+	}
+
+	st, ok := ts.Type.(*ast.StructType)
+	if !ok {
+		return nil, nil, fmt.Errorf("type '%s' is not a struct", name)
+	}
+
+	ent = &entity{Name: name}
+	var keyTag string
+	var pkgs []string
+	for _, f := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if f.Tag != nil {
+			s, _ := strconv.Unquote(f.Tag.Value)
+			tag = reflect.StructTag(s)
+		}
+
+		if v, ok := tag.Lookup("ddb"); ok {
+			if keyTag != "" {
+				return nil, nil, fmt.Errorf("type '%s' has more than one ddb tag", name)
+			}
+			keyTag = v
+		}
+
+		if len(f.Names) == 0 || tag.Get("dynamodbav") == "-" {
+			continue // embedded fields and fields that are not stored
+		}
+
+		var typ bytes.Buffer
+		if err = printer.Fprint(&typ, src.fset, f.Type); err != nil {
+			return nil, nil, fmt.Errorf("failed to print type of field: %w", err)
+		}
+
+		for _, n := range f.Names {
+			if !n.IsExported() {
+				continue
+			}
+
+			fld := field{Name: n.Name, Type: typ.String(), Tag: fmt.Sprintf(`dynamodbav:"%s"`, n.Name)}
+			if av, ok := tag.Lookup("dynamodbav"); ok {
+				fld.Tag = fmt.Sprintf(`dynamodbav:%q`, av)
+			}
+
+			fld.Sample = sample(fld.Type, len(ent.Fields))
+			ent.Fields = append(ent.Fields, fld)
+		}
+
+		pkgs = append(pkgs, referencedPackages(f.Type)...)
+	}
+
+	if keyTag == "" {
+		return nil, nil, fmt.Errorf("type '%s' has no ddb tag, for example: `ddb:\"pk=USER#{ID}\"`", name)
+	}
+
+	if err = ent.parseKeys(keyTag); err != nil {
+		return nil, nil, fmt.Errorf("invalid ddb tag on type '%s': %w", name, err)
+	}
+
+	return ent, src.imports(src.files[name], pkgs), nil
+}
+
+// imports returns the import paths of a file for the provided package names
+func (src *source) imports(f *ast.File, pkgs []string) (paths []string) {
+	for _, imp := range f.Imports {
+		p, _ := strconv.Unquote(imp.Path.Value)
+		name := path.Base(p)
+		if imp.Name != nil {
+			name = imp.Name.Name
+			p = name + " " + strconv.Quote(p)
+		} else {
+			p = strconv.Quote(p)
+		}
+
+		for _, pkg := range pkgs {
+			if pkg == name {
+				paths = append(paths, p)
+				break
+			}
+		}
+	}
+
+	sort.Strings(paths)
+	return
+}
+
+// referencedPackages returns the names of the packages that a type expression refers to
+func referencedPackages(expr ast.Expr) (names []string) {
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				names = append(names, id.Name)
+			}
+		}
+		return true
+	})
+	return
+}
+
+// parseKeys parses a ddb tag such as "pk=USER#{ID},sk=PROFILE"
+func (ent *entity) parseKeys(tag string) (err error) {
+	for _, part := range strings.Split(tag, ",") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("expected key=template, got: '%s'", part)
+		}
+
+		var k *key
+		if k, err = ent.parseKey(kv[0], kv[1]); err != nil {
+			return err
+		}
+
+		switch kv[0] {
+		case "pk":
+			ent.PK = k
+		case "sk":
+			ent.SK = k
+		default:
+			return fmt.Errorf("unsupported key '%s', expected 'pk' or 'sk'", kv[0])
+		}
+	}
+
+	if ent.PK == nil {
+		return fmt.Errorf("no partition key template")
+	}
+
+	for _, f := range ent.Fields {
+		if f.Tag == `dynamodbav:"pk"` || f.Tag == `dynamodbav:"sk"` ||
+			strings.HasPrefix(f.Tag, `dynamodbav:"pk,`) || strings.HasPrefix(f.Tag, `dynamodbav:"sk,`) {
+			return fmt.Errorf("field '%s' is stored as a key attribute", f.Name)
+		}
+
+		// the item holds the keys in fields with these names
+		if f.Name == "PK" || f.Name == "SK" {
+			return fmt.Errorf("field '%s' clashes with the key field of the item", f.Name)
+		}
+	}
+
+	return nil
+}
+
+// parseKey turns a key template into a go expression and the key of the sample values
+func (ent *entity) parseKey(attr, tmpl string) (*key, error) {
+	if strings.ContainsAny(placeholder.ReplaceAllString(tmpl, ""), "{}") {
+		return nil, fmt.Errorf("template '%s' has an invalid placeholder", tmpl)
+	}
+
+	var args []string
+	var unknown []string
+	predictable := true
+	format := placeholder.ReplaceAllStringFunc(strings.ReplaceAll(tmpl, "%", "%%"), func(m string) string {
+		f := ent.field(m[1 : len(m)-1])
+		if f == nil {
+			unknown = append(unknown, m)
+			return ""
+		}
+
+		args = append(args, "e."+f.Name)
+		predictable = predictable && f.Sample != ""
+		return "%v"
+	})
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("template '%s' refers to unknown or unexported field(s): %s",
+			tmpl, strings.Join(unknown, ", "))
+	}
+
+	k := &key{Attr: attr}
+	if len(args) == 0 {
+		k.Expr, k.Sample = strconv.Quote(tmpl), tmpl
+		return k, nil
+	}
+
+	k.Expr = fmt.Sprintf("fmt.Sprintf(%s, %s)", strconv.Quote(format), strings.Join(args, ", "))
+	if predictable {
+		k.Sample = placeholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+			return sampleString(ent.field(m[1 : len(m)-1]).Sample)
+		})
+	}
+
+	return k, nil
+}
+
+// field returns the stored field with the provided name, or nil if there is none
+func (ent *entity) field(name string) *field {
+	for i := range ent.Fields {
+		if ent.Fields[i].Name == name {
+			return &ent.Fields[i]
+		}
+	}
+	return nil
+}
+
+// KeyList returns the key templates of the entity
+func (ent *entity) KeyList() (keys []*key) {
+	for _, k := range []*key{ent.PK, ent.SK} {
+		if k != nil {
+			keys = append(keys, k)
+		}
+	}
+	return
+}
+
+// sample returns a go literal for a field of a basic type, or an empty string for other types
+func sample(typ string, i int) string {
+	switch typ {
+	case "string":
+		return strconv.Quote(fmt.Sprintf("v%d", i+1))
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return strconv.Itoa(i + 1)
+	case "float32", "float64":
+		return strconv.Itoa(i+1) + ".5"
+	case "bool":
+		return "true"
+	}
+	return ""
+}
+
+// sampleString returns how a sample literal is formatted with %v
+func sampleString(lit string) string {
+	if s, err := strconv.Unquote(lit); err == nil {
+		return s
+	}
+	return lit
+}
+
+// generate renders the source code of the item and mapping methods, and of the round-trip tests
+func generate(pkg string, ents []*entity, imports []string) (code, test []byte, err error) {
+	data := struct {
+		Package  string
+		Entities []*entity
+		Imports  []string
+	}{pkg, ents, imports}
+
+	for _, out := range []struct {
+		tmpl *template.Template
+		dst  *[]byte
+	}{{codeTmpl, &code}, {testTmpl, &test}} {
+		var buf bytes.Buffer
+		if err = out.tmpl.Execute(&buf, data); err != nil {
+			return nil, nil, fmt.Errorf("failed to render: %w", err)
+		}
+
+		if *out.dst, err = format.Source(buf.Bytes()); err != nil {
+			return nil, nil, fmt.Errorf("failed to format: %w\n%s", err, buf.String())
+		}
+	}
+
+	return
+}
+
+var codeTmpl = template.Must(template.New("code").Parse(`// Code generated by ddbgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"fmt"{{range .Imports}}
+	{{.}}{{end}}
+
+	"github.com/gohandle/ddb"
+)
+{{range .Entities}}
+// {{.Name}}Item is the item that {{.Name}} entities are stored as
+type {{.Name}}Item struct {
+	PK string ` + "`" + `dynamodbav:"pk"` + "`" + `
+{{- if .SK}}
+	SK string ` + "`" + `dynamodbav:"sk"` + "`" + `{{end}}
+{{- range .Fields}}
+	{{.Name}} {{.Type}} ` + "`" + `{{.Tag}}` + "`" + `{{end}}
+}
+
+// Keys returns the names of the key attributes
+func ({{.Name}}Item) Keys() (pk, sk string) { return "pk", "{{if .SK}}sk{{end}}" }
+
+// Item maps the entity onto its item
+func (e {{.Name}}) Item() ddb.Item {
+	return &{{.Name}}Item{
+		PK: {{.PK.Expr}},
+{{- if .SK}}
+		SK: {{.SK.Expr}},{{end}}
+{{- range .Fields}}
+		{{.Name}}: e.{{.Name}},{{end}}
+	}
+}
+
+// FromItem maps the item back onto the entity
+func (e *{{.Name}}) FromItem(it ddb.Item) error {
+	item, ok := it.(*{{.Name}}Item)
+	if !ok {
+		return fmt.Errorf("unexpected item for {{.Name}}: %T", it)
+	}
+{{range .Fields}}
+	e.{{.Name}} = item.{{.Name}}{{end}}
+	return nil
+}
+{{end}}`))
+
+var testTmpl = template.Must(template.New("test").Parse(`// Code generated by ddbgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+{{range .Entities}}
+func Test{{.Name}}ItemRoundTrip(t *testing.T) {
+	exp := {{.Name}}{ {{- range .Fields}}{{if .Sample}}{{.Name}}: {{.Sample}}, {{end}}{{end -}} }
+	av, err := dynamodbattribute.MarshalMap(exp.Item())
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+{{range .KeyList}}{{if .Sample}}
+	if v := av["{{.Attr}}"]; v == nil || v.S == nil || *v.S != {{printf "%q" .Sample}} {
+		t.Fatalf("got: %v", v)
+	}
+{{end}}{{end}}
+	var it {{.Name}}Item
+	if err = dynamodbattribute.UnmarshalMap(av, &it); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	var act {{.Name}}
+	if err = act.FromItem(&it); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatalf("got: %v", act)
+	}
+}
+{{end}}`))
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateExample(t *testing.T) {
+	dir := t.TempDir()
+	src, err := os.ReadFile(filepath.Join("internal", "example", "example.go"))
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, "example.go"), src, 0644); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if err = run(dir, []string{"User", "Order"}, "", true); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	// the generated example is committed, it must be up to date
+	for _, name := range []string{"user_ddb.go", "user_ddb_test.go"} {
+		exp, err := os.ReadFile(filepath.Join("internal", "example", name))
+		if err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		act, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || !bytes.Equal(act, exp) {
+			t.Fatalf("%s is out of date, run go generate: %v", name, err)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	for src, exp := range map[string]string{
+		"type E struct{ ID int }": "has no ddb tag",
+		"type E int":              "is not a struct",
+		"type E struct{ _ struct{} `ddb:\"sk=A\"`\n ID int }":                       "no partition key",
+		"type E struct{ _ struct{} `ddb:\"pk=A#{Name}\"`\n ID int }":                "unknown or unexported field(s): {Name}",
+		"type E struct{ _ struct{} `ddb:\"pk=A#{ID\"`\n ID int }":                   "invalid placeholder",
+		"type E struct{ _ struct{} `ddb:\"pk=A,gsi=B\"`\n ID int }":                 "unsupported key 'gsi'",
+		"type E struct{ _ struct{} `ddb:\"pk=A\"`\n ID int `dynamodbav:\"pk\"` }":   "is stored as a key attribute",
+		"type E struct{ _ struct{} `ddb:\"pk=A#{ID}\"`\n ID int\n PK string }":      "field 'PK' clashes with the key field",
+		"type E struct{ _ struct{} `ddb:\"pk=A\"`\n SK string `dynamodbav:\"s\"` }": "field 'SK' clashes with the key field",
+	} {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "e.go"), []byte("package e\n"+src), 0644); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		if err := run(dir, []string{"E"}, "", true); err == nil || !strings.Contains(err.Error(), exp) {
+			t.Fatalf("%s, exp: %s, got: %v", src, exp, err)
+		}
+	}
+}
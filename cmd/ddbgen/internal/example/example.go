@@ -0,0 +1,25 @@
+// Package example holds entities that ddbgen generates code for, it is used to test the generator
+package example
+
+import "time"
+
+//go:generate go run github.com/gohandle/ddb/cmd/ddbgen -type User,Order
+
+// User is stored in its own partition with a fixed sort key
+type User struct {
+	_       struct{} `ddb:"pk=USER#{ID},sk=PROFILE"`
+	ID      int
+	Name    string `dynamodbav:"name"`
+	Admin   bool   `dynamodbav:"admin,omitempty"`
+	Created time.Time
+	cache   string
+}
+
+// Order is stored in the partition of the user that placed it
+type Order struct {
+	_      struct{} `ddb:"pk=USER#{UserID},sk=ORDER#{ID}"`
+	UserID int
+	ID     string
+	Total  float64 `dynamodbav:"total"`
+	Notes  string  `dynamodbav:"-"`
+}
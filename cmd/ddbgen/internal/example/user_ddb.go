@@ -0,0 +1,85 @@
+// Code generated by ddbgen. DO NOT EDIT.
+
+package example
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gohandle/ddb"
+)
+
+// UserItem is the item that User entities are stored as
+type UserItem struct {
+	PK      string    `dynamodbav:"pk"`
+	SK      string    `dynamodbav:"sk"`
+	ID      int       `dynamodbav:"ID"`
+	Name    string    `dynamodbav:"name"`
+	Admin   bool      `dynamodbav:"admin,omitempty"`
+	Created time.Time `dynamodbav:"Created"`
+}
+
+// Keys returns the names of the key attributes
+func (UserItem) Keys() (pk, sk string) { return "pk", "sk" }
+
+// Item maps the entity onto its item
+func (e User) Item() ddb.Item {
+	return &UserItem{
+		PK:      fmt.Sprintf("USER#%v", e.ID),
+		SK:      "PROFILE",
+		ID:      e.ID,
+		Name:    e.Name,
+		Admin:   e.Admin,
+		Created: e.Created,
+	}
+}
+
+// FromItem maps the item back onto the entity
+func (e *User) FromItem(it ddb.Item) error {
+	item, ok := it.(*UserItem)
+	if !ok {
+		return fmt.Errorf("unexpected item for User: %T", it)
+	}
+
+	e.ID = item.ID
+	e.Name = item.Name
+	e.Admin = item.Admin
+	e.Created = item.Created
+	return nil
+}
+
+// OrderItem is the item that Order entities are stored as
+type OrderItem struct {
+	PK     string  `dynamodbav:"pk"`
+	SK     string  `dynamodbav:"sk"`
+	UserID int     `dynamodbav:"UserID"`
+	ID     string  `dynamodbav:"ID"`
+	Total  float64 `dynamodbav:"total"`
+}
+
+// Keys returns the names of the key attributes
+func (OrderItem) Keys() (pk, sk string) { return "pk", "sk" }
+
+// Item maps the entity onto its item
+func (e Order) Item() ddb.Item {
+	return &OrderItem{
+		PK:     fmt.Sprintf("USER#%v", e.UserID),
+		SK:     fmt.Sprintf("ORDER#%v", e.ID),
+		UserID: e.UserID,
+		ID:     e.ID,
+		Total:  e.Total,
+	}
+}
+
+// FromItem maps the item back onto the entity
+func (e *Order) FromItem(it ddb.Item) error {
+	item, ok := it.(*OrderItem)
+	if !ok {
+		return fmt.Errorf("unexpected item for Order: %T", it)
+	}
+
+	e.UserID = item.UserID
+	e.ID = item.ID
+	e.Total = item.Total
+	return nil
+}
@@ -0,0 +1,70 @@
+// Code generated by ddbgen. DO NOT EDIT.
+
+package example
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+func TestUserItemRoundTrip(t *testing.T) {
+	exp := User{ID: 1, Name: "v2", Admin: true}
+	av, err := dynamodbattribute.MarshalMap(exp.Item())
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if v := av["pk"]; v == nil || v.S == nil || *v.S != "USER#1" {
+		t.Fatalf("got: %v", v)
+	}
+
+	if v := av["sk"]; v == nil || v.S == nil || *v.S != "PROFILE" {
+		t.Fatalf("got: %v", v)
+	}
+
+	var it UserItem
+	if err = dynamodbattribute.UnmarshalMap(av, &it); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	var act User
+	if err = act.FromItem(&it); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatalf("got: %v", act)
+	}
+}
+
+func TestOrderItemRoundTrip(t *testing.T) {
+	exp := Order{UserID: 1, ID: "v2", Total: 3.5}
+	av, err := dynamodbattribute.MarshalMap(exp.Item())
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if v := av["pk"]; v == nil || v.S == nil || *v.S != "USER#1" {
+		t.Fatalf("got: %v", v)
+	}
+
+	if v := av["sk"]; v == nil || v.S == nil || *v.S != "ORDER#v2" {
+		t.Fatalf("got: %v", v)
+	}
+
+	var it OrderItem
+	if err = dynamodbattribute.UnmarshalMap(av, &it); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	var act Order
+	if err = act.FromItem(&it); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatalf("got: %v", act)
+	}
+}
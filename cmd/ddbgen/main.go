@@ -0,0 +1,96 @@
+// Command ddbgen generates the item type and the Itemizer and Deitemizer methods for entities.
+// The keys of an entity are described by a ddb tag, usually on a blank field:
+//
+//	//go:generate go run github.com/gohandle/ddb/cmd/ddbgen -type User
+//	type User struct {
+//		_    struct{} `ddb:"pk=USER#{ID},sk=PROFILE"`
+//		ID   int
+//		Name string `dynamodbav:"name"`
+//	}
+//
+// Placeholders in the key templates refer to fields of the entity. Every exported field is also
+// stored as an attribute of the item, such that it can be mapped back onto the entity. The item
+// holds the keys in its PK and SK fields, so entities can't have fields with those names, or
+// fields that are stored as the "pk" or "sk" attribute. Round-trip tests are generated next to
+// the code.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	types := flag.String("type", "", "comma-separated list of entity type names, required")
+	output := flag.String("output", "", "output file name, default: <first type>_ddb.go in lower case")
+	tests := flag.Bool("tests", true, "generate round-trip tests into <output>_test.go")
+	flag.Parse()
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	if err := run(dir, strings.Split(*types, ","), *output, *tests); err != nil {
+		log.Fatalf("ddbgen: %v", err)
+	}
+}
+
+// run generates the code for the types in the package directory
+func run(dir string, types []string, output string, tests bool) error {
+	if len(types) == 0 || types[0] == "" {
+		return fmt.Errorf("no types, use the -type flag")
+	}
+
+	src, err := parseDir(dir)
+	if err != nil {
+		return err
+	}
+
+	var ents []*entity
+	seen := map[string]bool{}
+	var imports []string
+	for _, name := range types {
+		ent, imps, err := src.entity(strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+
+		for _, imp := range imps {
+			if !seen[imp] {
+				seen[imp] = true
+				imports = append(imports, imp)
+			}
+		}
+
+		ents = append(ents, ent)
+	}
+
+	code, test, err := generate(src.pkg, ents, imports)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = strings.ToLower(ents[0].Name) + "_ddb.go"
+	}
+
+	output = filepath.Join(dir, output)
+	if err = os.WriteFile(output, code, 0644); err != nil {
+		return fmt.Errorf("failed to write code: %w", err)
+	}
+
+	if !tests {
+		return nil
+	}
+
+	if err = os.WriteFile(strings.TrimSuffix(output, ".go")+"_test.go", test, 0644); err != nil {
+		return fmt.Errorf("failed to write tests: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,18 @@
+// Command ddblocal runs an in-memory DynamoDB that can be used by any DynamoDB client
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/gohandle/ddb/ddblocal"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8000", "address to listen on")
+	flag.Parse()
+
+	log.Printf("serving in-memory DynamoDB on http://%s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, ddblocal.New(nil)))
+}
@@ -0,0 +1,56 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// ErrConsistentReadOnGlobalIndex is returned when a query or scan asks for a consistent read
+// of a global secondary index that was declared with the Schemas option, DynamoDB only supports
+// them on tables and local indexes.
+var ErrConsistentReadOnGlobalIndex = errors.New("consistent reads are not supported on global secondary indexes")
+
+// consistentReadKey is the context key that holds the consistent read override
+type consistentReadKey struct{}
+
+// WithConsistentRead returns a context that overwrites the ConsistentRead option for the reads
+// that run with it, or a context derived from it. Reads are strongly consistent if 'consistent'
+// is true and eventually consistent otherwise. Transactional reads are always consistent.
+func WithConsistentRead(ctx context.Context, consistent bool) context.Context {
+	return context.WithValue(ctx, consistentReadKey{}, consistent)
+}
+
+// consistentRead returns the ConsistentRead parameter for reads that run with the context. It
+// returns nil if neither the context nor the options ask for it, leaving the input as it is.
+func (opts Options) consistentRead(ctx context.Context) *bool {
+	if v, ok := ctx.Value(consistentReadKey{}).(bool); ok {
+		return aws.Bool(v)
+	}
+
+	if !opts.enableConsistentRead {
+		return nil
+	}
+
+	return aws.Bool(true)
+}
+
+// indexConsistentRead returns the ConsistentRead parameter of a query or scan. The options and
+// context only make reads of tables, and of indexes that were declared local with the Schemas
+// option, consistent: other indexes may be global and are read as the input asks. A consistent
+// read that the input asks for of an index declared global is refused, such that it doesn't cost
+// a roundtrip to DynamoDB.
+func (opts Options) indexConsistentRead(ctx context.Context, consistent *bool, table, index *string) (*bool, error) {
+	idx, declared := opts.indexes[indexKey{aws.StringValue(table), aws.StringValue(index)}]
+	if cr := opts.consistentRead(ctx); cr != nil && (index == nil || !*cr || (declared && idx.Local)) {
+		return cr, nil
+	}
+
+	if !aws.BoolValue(consistent) || index == nil || !declared || idx.Local {
+		return consistent, nil
+	}
+
+	return nil, fmt.Errorf("%w: index %s", ErrConsistentReadOnGlobalIndex, aws.StringValue(index))
+}
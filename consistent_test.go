@@ -0,0 +1,138 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/gohandle/ddb/internal/ddbtest"
+)
+
+func TestConsistentRead(t *testing.T) {
+	tbl := table2(t.Name())
+	mddb := ddbtest.MemDB(t, tbl.createInput())
+
+	if _, err := NewWriter().
+		Put(tbl.Put1(&table2Entity{ID: 1, Kind: 1})).
+		Put(tbl.Put1(&table2Entity{ID: 2, Kind: 1})).
+		Run(context.Background(), mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	// eventually consistent reads of small items consume half a unit, consistent reads a full one
+	for _, c := range []struct {
+		ctx  context.Context
+		opts []Option
+		exp  float64
+	}{
+		{context.Background(), nil, 0.5},
+		{context.Background(), []Option{ConsistentRead()}, 1},
+		{WithConsistentRead(context.Background(), true), nil, 1},
+		{WithConsistentRead(context.Background(), false), []Option{ConsistentRead()}, 0.5},
+	} {
+		opts := append([]Option{EnableConsumedCapacity()}, c.opts...)
+
+		ctx, capa := WithCapacity(c.ctx)
+		if _, err := NewReader(opts...).Get(tbl.Get1(1)).Run(ctx, mddb); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		if act := capa.Total().Read; act != c.exp {
+			t.Fatalf("single get, exp: %v, got: %v", c.exp, act)
+		}
+
+		ctx, capa = WithCapacity(c.ctx)
+		if _, err := NewReader(append(opts, EnableBatchReads())...).
+			Get(tbl.Get1(1)).Get(tbl.Get1(2)).Run(ctx, mddb); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		if act := capa.Total().Read; act != c.exp*2 {
+			t.Fatalf("batch get, exp: %v, got: %v", c.exp*2, act)
+		}
+
+		ctx, capa = WithCapacity(c.ctx)
+		if _, err := Scan(e.NewBuilder(), dynamodb.ScanInput{TableName: aws.String(string(tbl))}, opts...).Run(ctx, mddb); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		if act := capa.Total().Read; act != c.exp {
+			t.Fatalf("scan, exp: %v, got: %v", c.exp, act)
+		}
+	}
+
+	// the option doesn't apply to indexes that may be global
+	for _, opts := range [][]Option{nil, {Schemas(mustSchema(string(tbl), table2Item{}))}} {
+		ctx, capa := WithCapacity(context.Background())
+		b, q := tbl.ByKind(1)
+		if _, err := Query(b, q, append(opts, ConsistentRead(), EnableConsumedCapacity())...).Run(ctx, mddb); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		if act := capa.Total().Read; act != 0.5 {
+			t.Fatalf("global index, got: %v", act)
+		}
+	}
+
+	// without the schema an input that asks for it is send to DynamoDB
+	b, q := tbl.ByKind(1)
+	q.SetConsistentRead(true)
+	_, err := Query(b, q).Run(context.Background(), mddb)
+	if err == nil || errors.Is(err, ErrConsistentReadOnGlobalIndex) {
+		t.Fatalf("got: %v", err)
+	}
+
+	// with the schema declared the read is refused without calling DynamoDB
+	b, q = tbl.ByKind(1)
+	q.SetConsistentRead(true)
+	_, err = Query(b, q, Schemas(mustSchema(string(tbl), table2Item{}))).Run(context.Background(), nil)
+	if !errors.Is(err, ErrConsistentReadOnGlobalIndex) {
+		t.Fatalf("got: %v", err)
+	}
+
+	b, q = tbl.ByKind(1)
+	if _, err = Query(b, q).Run(WithConsistentRead(context.Background(), false), mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+}
+
+type localItem struct {
+	PK   string `dynamodbav:"pk"`
+	SK   string `dynamodbav:"sk"`
+	Name string `dynamodbav:"name"`
+}
+
+func (localItem) Keys() (pk, sk string) { return "pk", "sk" }
+
+func (localItem) Indexes() []Index { return []Index{{Name: "by-name", SK: "name", Local: true}} }
+
+func TestConsistentReadLocalIndex(t *testing.T) {
+	s := mustSchema(t.Name(), localItem{})
+	mddb := ddbtest.MemDB(t, s.CreateTableInput())
+
+	// the option applies to local indexes once they are declared
+	for _, c := range []struct {
+		opts []Option
+		exp  float64
+	}{
+		{[]Option{ConsistentRead()}, 0.5},
+		{[]Option{ConsistentRead(), Schemas(s)}, 1},
+	} {
+		var q dynamodb.QueryInput
+		q.SetTableName(t.Name())
+		q.SetIndexName("by-name")
+
+		ctx, capa := WithCapacity(context.Background())
+		b := e.NewBuilder().WithKeyCondition(e.Key("pk").Equal(e.Value("a")))
+		if _, err := Query(b, q, append(c.opts, EnableConsumedCapacity())...).Run(ctx, mddb); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		if act := capa.Total().Read; act != c.exp {
+			t.Fatalf("exp: %v, got: %v", c.exp, act)
+		}
+	}
+}
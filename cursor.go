@@ -0,0 +1,216 @@
+package ddb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ErrInvalidCursor is returned when a cursor can't be decoded, was tampered with or was created
+// for another table or index.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PagedResult is implemented by the results of queries and scans. Cursor returns an opaque token
+// that encodes the position of the result: resuming from it continues with the item after the
+// one that was last returned by Next. It returns an empty string when there are no more items.
+// Cursors are signed and, optionally, encrypted with the secret of the CursorSecret option.
+type PagedResult interface {
+	Result
+	Cursor() (string, error)
+}
+
+// cursor versions, the first byte of every token
+const (
+	cursorSigned    byte = 1
+	cursorEncrypted byte = 2
+)
+
+// cursorKeyValue holds a key attribute value, these can only be strings, numbers or binary
+type cursorKeyValue struct {
+	S *string `json:"s,omitempty"`
+	N *string `json:"n,omitempty"`
+	B []byte  `json:"b,omitempty"`
+}
+
+// cursor is the position of a paginated result: the start key of the page that is being
+// iterated and the offset of the next item within it.
+type cursor struct {
+	Table  string                    `json:"t"`
+	Index  string                    `json:"i,omitempty"`
+	Key    map[string]cursorKeyValue `json:"k,omitempty"`
+	Offset int                       `json:"o,omitempty"`
+}
+
+// newCursor inits a cursor, it fails if the key holds values that can't be a key attribute
+func newCursor(table, index *string, key map[string]*dynamodb.AttributeValue, offset int) (c cursor, err error) {
+	c = cursor{Table: aws.StringValue(table), Index: aws.StringValue(index), Offset: offset}
+	for name, av := range key {
+		if av == nil || (av.S == nil && av.N == nil && av.B == nil) {
+			return c, fmt.Errorf("unsupported value for key attribute '%s': %v", name, av)
+		}
+
+		if c.Key == nil {
+			c.Key = map[string]cursorKeyValue{}
+		}
+		c.Key[name] = cursorKeyValue{S: av.S, N: av.N, B: av.B}
+	}
+
+	return
+}
+
+// startKey returns the cursor's key as an ExclusiveStartKey
+func (c cursor) startKey() (key map[string]*dynamodb.AttributeValue) {
+	for name, v := range c.Key {
+		if key == nil {
+			key = map[string]*dynamodb.AttributeValue{}
+		}
+		key[name] = &dynamodb.AttributeValue{S: v.S, N: v.N, B: v.B}
+	}
+	return
+}
+
+// cursorKeys derives the signing and encryption key from the cursor secret
+func cursorKeys(secret []byte) (macKey, encKey []byte) {
+	derive := func(label string) []byte {
+		h := hmac.New(sha256.New, secret)
+		h.Write([]byte(label))
+		return h.Sum(nil)
+	}
+
+	return derive("ddb cursor signing"), derive("ddb cursor encryption")
+}
+
+// encodeCursor turns the cursor into a token
+func encodeCursor(opts Options, c cursor) (string, error) {
+	if len(opts.cursorSecret) == 0 {
+		return "", fmt.Errorf("no cursor secret configured, see the CursorSecret option")
+	}
+
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+
+	macKey, encKey := cursorKeys(opts.cursorSecret)
+	if !opts.encryptCursors {
+		h := hmac.New(sha256.New, macKey)
+		tok := append([]byte{cursorSigned}, payload...)
+		h.Write(tok)
+		return base64.RawURLEncoding.EncodeToString(h.Sum(tok)), nil
+	}
+
+	aead, err := cursorAEAD(encKey)
+	if err != nil {
+		return "", err
+	}
+
+	tok := make([]byte, 1+aead.NonceSize())
+	tok[0] = cursorEncrypted
+	if _, err = rand.Read(tok[1:]); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	tok = aead.Seal(tok, tok[1:], payload, tok[:1])
+	return base64.RawURLEncoding.EncodeToString(tok), nil
+}
+
+// decodeCursor verifies and decodes a token for a query or scan of a table or index
+func decodeCursor(opts Options, s string, table, index *string) (c cursor, err error) {
+	if len(opts.cursorSecret) == 0 {
+		return c, fmt.Errorf("no cursor secret configured, see the CursorSecret option")
+	}
+
+	tok, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(tok) < 1 {
+		return c, fmt.Errorf("%w: malformed token", ErrInvalidCursor)
+	}
+
+	var payload []byte
+	macKey, encKey := cursorKeys(opts.cursorSecret)
+	switch tok[0] {
+	case cursorSigned:
+		if len(tok) < 1+sha256.Size {
+			return c, fmt.Errorf("%w: malformed token", ErrInvalidCursor)
+		}
+
+		h := hmac.New(sha256.New, macKey)
+		h.Write(tok[:len(tok)-sha256.Size])
+		if !hmac.Equal(h.Sum(nil), tok[len(tok)-sha256.Size:]) {
+			return c, fmt.Errorf("%w: signature mismatch", ErrInvalidCursor)
+		}
+
+		payload = tok[1 : len(tok)-sha256.Size]
+	case cursorEncrypted:
+		aead, err := cursorAEAD(encKey)
+		if err != nil {
+			return c, err
+		}
+
+		if len(tok) < 1+aead.NonceSize() {
+			return c, fmt.Errorf("%w: malformed token", ErrInvalidCursor)
+		}
+
+		nonce := tok[1 : 1+aead.NonceSize()]
+		if payload, err = aead.Open(nil, nonce, tok[1+aead.NonceSize():], tok[:1]); err != nil {
+			return c, fmt.Errorf("%w: failed to decrypt", ErrInvalidCursor)
+		}
+	default:
+		return c, fmt.Errorf("%w: unsupported version %d", ErrInvalidCursor, tok[0])
+	}
+
+	if err = json.Unmarshal(payload, &c); err != nil {
+		return c, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	if c.Table != aws.StringValue(table) || c.Index != aws.StringValue(index) || c.Offset < 0 {
+		return c, fmt.Errorf("%w: created for another table or index", ErrInvalidCursor)
+	}
+
+	return
+}
+
+// cursorAEAD inits the cipher that encrypts cursors
+func cursorAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init cipher: %w", err)
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// pageCursor returns the cursor of a page that was fetched with 'start' after the item at
+// 'pos' was returned. It returns an empty string if the page was the last one and has no items
+// left.
+func pageCursor(
+	opts Options,
+	table, index *string,
+	start, last map[string]*dynamodb.AttributeValue,
+	pos, n int,
+) (string, error) {
+	var c cursor
+	var err error
+	switch {
+	case pos+1 < n:
+		c, err = newCursor(table, index, start, pos+1)
+	case last != nil:
+		c, err = newCursor(table, index, last, 0)
+	default:
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return encodeCursor(opts, c)
+}
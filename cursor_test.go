@@ -0,0 +1,226 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/gohandle/ddb/internal/ddbtest"
+)
+
+func TestCursorEncoding(t *testing.T) {
+	key := map[string]*dynamodb.AttributeValue{"pk": {S: aws.String("a")}, "n": {N: aws.String("1")}}
+	c, err := newCursor(aws.String("tbl"), aws.String("gsi1"), key, 3)
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	for _, opts := range [][]Option{
+		{CursorSecret([]byte("secret"))},
+		{CursorSecret([]byte("secret")), EncryptCursors()},
+	} {
+		var o Options
+		o.Apply(opts...)
+
+		tok, err := encodeCursor(o, c)
+		if err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		act, err := decodeCursor(o, tok, aws.String("tbl"), aws.String("gsi1"))
+		if err != nil || !reflect.DeepEqual(act, c) || !reflect.DeepEqual(act.startKey(), key) {
+			t.Fatalf("got: %v %v", act, err)
+		}
+
+		if _, err = decodeCursor(o, tok, aws.String("tbl"), nil); !errors.Is(err, ErrInvalidCursor) {
+			t.Fatalf("got: %v", err)
+		}
+
+		b := []byte(tok)
+		b[len(b)/2] ^= 1
+		if _, err = decodeCursor(o, string(b), aws.String("tbl"), aws.String("gsi1")); !errors.Is(err, ErrInvalidCursor) {
+			t.Fatalf("got: %v", err)
+		}
+
+		var other Options
+		other.Apply(CursorSecret([]byte("other")))
+		if _, err = decodeCursor(other, tok, aws.String("tbl"), aws.String("gsi1")); !errors.Is(err, ErrInvalidCursor) {
+			t.Fatalf("got: %v", err)
+		}
+	}
+
+	if _, err = encodeCursor(Options{}, c); err == nil {
+		t.Fatalf("expected error without secret")
+	}
+
+	if _, err = newCursor(aws.String("tbl"), nil,
+		map[string]*dynamodb.AttributeValue{"pk": {BOOL: aws.Bool(true)}}, 0); err == nil {
+		t.Fatalf("expected error for non-key value")
+	}
+}
+
+func TestResumeFromCursor(t *testing.T) {
+	ctx, tbl := context.Background(), table2(t.Name())
+	mddb := ddbtest.MemDB(t, tbl.createInput())
+
+	w := NewWriter(EnableBatchWrites())
+	for i := 0; i < 7; i++ {
+		w.Put(tbl.Put1(&table2Entity{ID: i, Kind: 1}))
+	}
+
+	if _, err := w.Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	opts := []Option{CursorSecret([]byte("secret")), EncryptCursors()}
+	scan := dynamodb.ScanInput{TableName: aws.String(string(tbl)), Limit: aws.Int64(3)}
+	for name, run := range map[string]func(cursor string) (Result, error){
+		"query": func(cursor string) (Result, error) {
+			b, q := tbl.ByKind(1)
+			return Query(b, q, opts...).StartAt(cursor).Run(ctx, mddb)
+		},
+		"scan": func(cursor string) (Result, error) {
+			return Scan(e.NewBuilder(), scan, opts...).StartAt(cursor).Run(ctx, mddb)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var exp []int
+			r, err := run("")
+			if err != nil {
+				t.Fatalf("got: %v", err)
+			}
+
+			for r.Next() {
+				var ent table2Entity
+				if err = r.Scan(&ent); err != nil {
+					t.Fatalf("got: %v", err)
+				}
+				exp = append(exp, ent.ID)
+			}
+
+			// resume after every item, including those at the start and end of a page
+			for n := 0; n <= len(exp); n++ {
+				var act []int
+				cur := ""
+				for i := 0; ; i++ {
+					r, err := run(cur)
+					if err != nil {
+						t.Fatalf("got: %v", err)
+					}
+
+					if i == 0 && n == 0 {
+						if cur, err = r.(PagedResult).Cursor(); err != nil {
+							t.Fatalf("got: %v", err)
+						}
+						continue
+					}
+
+					for r.Next() {
+						var ent table2Entity
+						if err = r.Scan(&ent); err != nil {
+							t.Fatalf("got: %v", err)
+						}
+						act = append(act, ent.ID)
+						if len(act) == n {
+							break
+						}
+					}
+
+					if cur, err = r.(PagedResult).Cursor(); err != nil {
+						t.Fatalf("got: %v", err)
+					}
+
+					if cur == "" {
+						break
+					}
+				}
+
+				if !reflect.DeepEqual(act, exp) {
+					t.Fatalf("resume after %d, exp: %v, got: %v", n, exp, act)
+				}
+			}
+		})
+	}
+}
+
+func TestMaxItems(t *testing.T) {
+	ctx, tbl := context.Background(), table2(t.Name())
+	mddb := ddbtest.MemDB(t, tbl.createInput())
+
+	w := NewWriter(EnableBatchWrites())
+	for i := 0; i < 20; i++ {
+		w.Put(tbl.Put1(&table2Entity{ID: i, Kind: i % 4}))
+	}
+
+	if _, err := w.Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	// with a page size of 1 most pages are empty after the filter is applied
+	opts := []Option{CursorSecret([]byte("secret"))}
+	b := e.NewBuilder().WithFilter(e.Name("kind").Equal(e.Value(1)))
+	in := dynamodb.ScanInput{TableName: aws.String(string(tbl)), Limit: aws.Int64(1)}
+
+	var ids []int
+	var pages int
+	for cur := ""; ; {
+		r, err := Scan(b, in, opts...).MaxItems(2).StartAt(cur).Run(ctx, mddb)
+		if err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		var n int
+		for r.Next() {
+			var ent table2Entity
+			if err = r.Scan(&ent); err != nil {
+				t.Fatalf("got: %v", err)
+			}
+			ids, n = append(ids, ent.ID), n+1
+		}
+
+		if r.Err() != nil || n > 2 {
+			t.Fatalf("got: %v %d", r.Err(), n)
+		}
+
+		pages++
+		if cur, err = r.(PagedResult).Cursor(); err != nil {
+			t.Fatalf("got: %v", err)
+		} else if cur == "" {
+			break
+		}
+	}
+
+	sort.Ints(ids)
+	if !reflect.DeepEqual(ids, []int{1, 5, 9, 13, 17}) || pages != 3 {
+		t.Fatalf("got: %v in %d pages", ids, pages)
+	}
+}
+
+func TestUnmarshalAllMaxItems(t *testing.T) {
+	ctx, tbl := context.Background(), table2(t.Name())
+	mddb := ddbtest.MemDB(t, tbl.createInput())
+
+	w := NewWriter(EnableBatchWrites())
+	for i := 0; i < 5; i++ {
+		w.Put(tbl.Put1(&table2Entity{ID: i, Kind: 1}))
+	}
+
+	if _, err := w.Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	r, err := Scan(e.NewBuilder(), dynamodb.ScanInput{TableName: aws.String(string(tbl))}).MaxItems(2).Run(ctx, mddb)
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	var ents []*table2Entity
+	if err = UnmarshalAll(r, &ents); err != nil || len(ents) != 2 || ents[1] == nil {
+		t.Fatalf("got: %v %v", ents, err)
+	}
+}
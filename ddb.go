@@ -1,8 +1,10 @@
 package ddb
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/service/dynamodb"
@@ -52,6 +54,36 @@ func mapFilter(
 	return
 }
 
+// keyString is a utility method that returns a string that uniquely identifies the
+// key attributes in 'key' so it can be used to match items returned by DynamoDB with
+// the keys that were requested.
+func keyString(key map[string]*dynamodb.AttributeValue) string {
+	names := make([]string, 0, len(key))
+	for name := range key {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		av := key[name]
+		switch {
+		case av == nil:
+			fmt.Fprintf(&b, "%q=NULL;", name)
+		case av.S != nil:
+			fmt.Fprintf(&b, "%q=S:%q;", name, *av.S)
+		case av.N != nil:
+			fmt.Fprintf(&b, "%q=N:%q;", name, *av.N)
+		case av.B != nil:
+			fmt.Fprintf(&b, "%q=B:%s;", name, base64.StdEncoding.EncodeToString(av.B))
+		default:
+			fmt.Fprintf(&b, "%q=%s;", name, av)
+		}
+	}
+
+	return b.String()
+}
+
 // exprBuild builds the expression but ignores errors that occure when
 // a zero value builder is built.
 func exprBuild(eb expression.Builder) (expr expression.Expression, err error) {
@@ -3,85 +3,39 @@ package ddb
 import (
 	"bytes"
 	"context"
+	"errors"
 	"log"
-	"math/rand"
-	"os"
-	"os/exec"
-	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/gohandle/ddb/internal/ddbtest"
+	"github.com/gohandle/ddb/memddb"
 )
 
-// withLocalDB will run local Dynamodb for the duration of the test while creating any tables
-// that are provided
-func withLocalDB(tb testing.TB, tbls ...*dynamodb.CreateTableInput) (ddb *dynamodb.DynamoDB) {
-	jexe, err := exec.LookPath("java")
-	if jexe == "" || err != nil {
-		tb.Fatalf("java not available in PATH: %v", err)
-	}
-
-	port := rand.Intn(65535-49152+1) + 49152
-	cmd := exec.Command(jexe,
-		"-D"+filepath.Join("internal", "localddb", "DynamoDBLocal_lib"),
-		"-jar", filepath.Join("internal", "localddb", "DynamoDBLocal.jar"),
-		"-inMemory", "--port", strconv.Itoa(port),
-	)
-
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	if err = cmd.Start(); err != nil {
-		tb.Fatalf("failed to start local ddb: %v", err)
-	}
-
-	tb.Cleanup(func() {
-		cmd.Process.Kill()
-	})
-
-	var sess *session.Session
-	if sess, err = session.NewSession(&aws.Config{
-		Region:   aws.String("eu-west-1"),
-		Endpoint: aws.String("http://localhost:" + strconv.Itoa(port)),
-	}); err != nil {
-		tb.Fatalf("failed to create local session: %v", err)
-	}
+// the in-memory implementation can be used wherever the sdk client is used
+var _ Dynamo = memddb.New()
+var _ TableDynamo = memddb.New()
 
-	ddb = dynamodb.New(sess)
-	for _, in := range tbls {
-		if _, err = ddb.CreateTable(in); err != nil {
-			tb.Fatalf("failed to create table %v: %v", in, err)
-		}
+// mustSchema derives the schema of a test table
+func mustSchema(table string, item Item) *Schema {
+	s, err := NewSchema(table, item)
+	if err != nil {
+		panic(err)
 	}
-
-	return
+	return s
 }
 
 // table1 describes a simple table that just has a string pk
 type table1 string
 
 func (tbl table1) createInput() *dynamodb.CreateTableInput {
-	return (&dynamodb.CreateTableInput{}).
-		SetTableName(string(tbl)).
-		SetProvisionedThroughput((&dynamodb.ProvisionedThroughput{}).
-			SetReadCapacityUnits(1).
-			SetWriteCapacityUnits(1)).
-		SetAttributeDefinitions(
-			[]*dynamodb.AttributeDefinition{
-				(&dynamodb.AttributeDefinition{}).
-					SetAttributeName("pk").SetAttributeType("S"),
-			}).
-		SetKeySchema(
-			[]*dynamodb.KeySchemaElement{
-				(&dynamodb.KeySchemaElement{}).
-					SetAttributeName("pk").
-					SetKeyType("HASH"),
-			})
+	return mustSchema(string(tbl), table1Item{}).CreateTableInput()
 }
 
 type table1Item struct {
@@ -154,7 +108,7 @@ func TestTable1End2End(t *testing.T) {
 
 	buf := bytes.NewBuffer(nil)
 	tbl := table1(t.Name())
-	ddb := LoggedDynamo(withLocalDB(t, tbl.createInput()), log.New(buf, "", 0))
+	ddb := LoggedDynamo(ddbtest.LocalDB(t, tbl.createInput()), log.New(buf, "", 0))
 
 	t.Run("put 10", func(t *testing.T) {
 		for i := 0; i < 10; i++ {
@@ -180,6 +134,30 @@ func TestTable1End2End(t *testing.T) {
 					t.Fatalf("got: %v", err)
 				}
 
+				t.Run("update nr 7 with return values", func(t *testing.T) {
+					r, err := Update(tbl.simpleUpd1(7, "name-7")).
+						ReturnValues(dynamodb.ReturnValueAllNew).
+						Run(ctx, ddb)
+					if err != nil {
+						t.Fatalf("got: %v", err)
+					}
+
+					if act := r.Len(); act != 1 {
+						t.Fatalf("got: %v", act)
+					}
+
+					var e table1Entity
+					for r.Next() {
+						if err := r.Scan(&e); err != nil {
+							t.Fatalf("got: %v", err)
+						}
+					}
+
+					if e.ID != 7 || e.Name != "name-7" {
+						t.Fatalf("got: %v", e)
+					}
+				})
+
 				t.Run("query nr 6", func(t *testing.T) {
 					r, err := Query(tbl.simpleQry1(6)).Run(ctx, ddb)
 					if err != nil {
@@ -257,7 +235,8 @@ func TestTable1End2End(t *testing.T) {
 						t.Fatalf("got: %v", err)
 					}
 
-					if act := strings.Join(names, ","); act != "name-9,name-7,name-5,name-8,foo" {
+					// items come in the order of the hash of their partition key
+					if act := strings.Join(names, ","); act != "name-9,name-8,name-7,name-5,foo" {
 						t.Fatalf("got: %v", act)
 					}
 				})
@@ -278,7 +257,8 @@ func TestTable1End2End(t *testing.T) {
 						names = append(names, et.Name)
 					}
 
-					if act := strings.Join(names, ","); act != "name-9,name-7,name-5,name-8,foo" {
+					// items come in the order of the hash of their partition key
+					if act := strings.Join(names, ","); act != "name-9,name-8,name-7,name-5,foo" {
 						t.Fatalf("got: %v", act)
 					}
 				})
@@ -291,47 +271,7 @@ func TestTable1End2End(t *testing.T) {
 type table2 string
 
 func (tbl table2) createInput() *dynamodb.CreateTableInput {
-	return (&dynamodb.CreateTableInput{}).
-		SetTableName(string(tbl)).
-		SetGlobalSecondaryIndexes(
-			[]*dynamodb.GlobalSecondaryIndex{
-				(&dynamodb.GlobalSecondaryIndex{}).
-					SetIndexName("gsi1").
-					SetProvisionedThroughput((&dynamodb.ProvisionedThroughput{}).
-						SetReadCapacityUnits(1).
-						SetWriteCapacityUnits(1)).
-					SetProjection((&dynamodb.Projection{}).
-						SetProjectionType(dynamodb.ProjectionTypeAll)).
-					SetKeySchema(
-						[]*dynamodb.KeySchemaElement{
-							(&dynamodb.KeySchemaElement{}).
-								SetAttributeName("kind").
-								SetKeyType("HASH"),
-						},
-					),
-			}).
-		SetProvisionedThroughput((&dynamodb.ProvisionedThroughput{}).
-			SetReadCapacityUnits(1).
-			SetWriteCapacityUnits(1)).
-		SetAttributeDefinitions(
-			[]*dynamodb.AttributeDefinition{
-				(&dynamodb.AttributeDefinition{}).
-					SetAttributeName("pk").SetAttributeType("S"),
-				(&dynamodb.AttributeDefinition{}).
-					SetAttributeName("sk").SetAttributeType("S"),
-				(&dynamodb.AttributeDefinition{}).
-					SetAttributeName("kind").SetAttributeType("N"),
-			}).
-		SetKeySchema(
-			[]*dynamodb.KeySchemaElement{
-				(&dynamodb.KeySchemaElement{}).
-					SetAttributeName("pk").
-					SetKeyType("HASH"),
-
-				(&dynamodb.KeySchemaElement{}).
-					SetAttributeName("sk").
-					SetKeyType("RANGE"),
-			})
+	return mustSchema(string(tbl), table2Item{}).CreateTableInput()
 }
 
 func (tbl table2) ByKind(kind int64) (b e.Builder, q dynamodb.QueryInput) {
@@ -354,6 +294,18 @@ func (tbl table2) Put1(e *table2Entity) (b e.Builder, op dynamodb.Put, it Itemiz
 	return b, op, e
 }
 
+func (tbl table2) Upd1(id int) (b e.Builder, op dynamodb.Update, it Itemizer) {
+	op.SetTableName(string(tbl))
+	return b.WithUpdate(
+		e.Set(e.Name("kind"), e.Value(2)),
+	), op, &table2Entity{ID: id}
+}
+
+func (tbl table2) Del1(id int) (b e.Builder, op dynamodb.Delete, it Itemizer) {
+	op.SetTableName(string(tbl))
+	return b, op, &table2Entity{ID: id}
+}
+
 func (tbl table2) Chc1(id int, isKind int) (b e.Builder, op dynamodb.ConditionCheck, it Itemizer) {
 	ent := &table2Entity{ID: id, Kind: isKind}
 	item := ent.Item().(*table2Item)
@@ -374,6 +326,10 @@ func (table2Item) Keys() (pk, sk string) {
 	return "pk", "sk"
 }
 
+func (table2Item) Indexes() []Index {
+	return []Index{{Name: "gsi1", PK: "kind"}}
+}
+
 type table2Entity struct {
 	ID   int
 	Kind int
@@ -397,7 +353,7 @@ func TestTable2End2End(t *testing.T) {
 	defer cancel()
 
 	tbl := table2(t.Name())
-	ddb := withLocalDB(t, tbl.createInput())
+	ddb := ddbtest.LocalDB(t, tbl.createInput())
 
 	t.Run("put tx", func(t *testing.T) {
 		w := NewWriter()
@@ -448,6 +404,160 @@ func TestTable2End2End(t *testing.T) {
 			}
 		})
 
+		t.Run("put tx with failing condition", func(t *testing.T) {
+			b, op, it := tbl.Put1(&table2Entity{ID: 1})
+			op.SetReturnValuesOnConditionCheckFailure(dynamodb.ReturnValuesOnConditionCheckFailureAllOld)
+
+			_, err := NewWriter().
+				Put(tbl.Put1(&table2Entity{ID: 50})).
+				Put(b.WithCondition(e.AttributeNotExists(e.Name("pk"))), op, it).
+				Run(ctx, ddb)
+
+			var cferr *ConditionFailedError
+			if !errors.As(err, &cferr) || cferr.Op != 1 {
+				t.Fatalf("got: %v", err)
+			}
+
+			var tcerr *TxCanceledError
+			if !errors.As(err, &tcerr) || len(tcerr.Reasons) != 2 || !tcerr.Has(TxReasonConditionalCheckFailed) {
+				t.Fatalf("got: %v", err)
+			}
+
+			if act := tcerr.Reasons[0].Code; act != TxReasonNone {
+				t.Fatalf("got: %v", act)
+			}
+
+			if act := aws.StringValue(tcerr.Reasons[1].Key["pk"].S); act != "1" {
+				t.Fatalf("got: %v", act)
+			}
+
+			r := cferr.Result()
+			if act := r.Len(); act != 1 {
+				t.Fatalf("got: %v", act)
+			}
+
+			var ent table2Entity
+			for r.Next() {
+				if err := r.Scan(&ent); err != nil {
+					t.Fatalf("got: %v", err)
+				}
+			}
+
+			if ent.ID != 1 {
+				t.Fatalf("got: %v", ent)
+			}
+		})
+
+		t.Run("idempotent put tx", func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				if _, err := NewWriter().
+					Put(tbl.Put1(&table2Entity{ID: 60})).
+					ClientRequestToken("idempotent-put-tx").
+					Run(ctx, ddb); err != nil {
+					t.Fatalf("got: %v", err)
+				}
+			}
+
+			_, err := NewWriter().
+				Put(tbl.Put1(&table2Entity{ID: 61})).
+				ClientRequestToken("idempotent-put-tx").
+				Run(ctx, ddb)
+
+			var imerr *IdempotencyMismatchError
+			if !errors.As(err, &imerr) || imerr.Token != "idempotent-put-tx" {
+				t.Fatalf("got: %v", err)
+			}
+		})
+
+		t.Run("get batch", func(t *testing.T) {
+			rd := NewReader(EnableBatchReads())
+			for _, i := range []int{3, 100, 1, 2, 0, 1} {
+				rd.Get(tbl.Get1(i))
+			}
+
+			r, err := rd.Run(ctx, ddb)
+			if err != nil {
+				t.Fatalf("got: %v", err)
+			}
+
+			if act := r.Len(); act != 5 {
+				t.Fatalf("got: %v", act)
+			}
+
+			var ids []string
+			for r.Next() {
+				var e table2Entity
+				if err := r.Scan(&e); err != nil {
+					t.Fatalf("got: %v", err)
+				}
+
+				ids = append(ids, strconv.Itoa(e.ID))
+			}
+
+			if act := strings.Join(ids, ","); act != "3,1,2,0,1" {
+				t.Fatalf("got: %v", act)
+			}
+		})
+
+		t.Run("get batch with projection", func(t *testing.T) {
+			for _, c := range []struct {
+				proj e.ProjectionBuilder
+				exp  string
+			}{
+				{e.NamesList(e.Name("pk"), e.Name("kind")), "kind,pk"},
+				{e.NamesList(e.Name("kind")), "kind"},
+			} {
+				rd := NewReader(EnableBatchReads())
+				for _, i := range []int{3, 1} {
+					b, get, it := tbl.Get1(i)
+					rd.Get(b.WithProjection(c.proj), get, it)
+				}
+
+				r, err := rd.Run(ctx, ddb)
+				if err != nil {
+					t.Fatalf("got: %v", err)
+				}
+
+				if act := r.Len(); act != 2 {
+					t.Fatalf("got: %v", act)
+				}
+
+				for r.Next() {
+					names := keyNames(r.(*result).current())
+					sort.Strings(names)
+					if act := strings.Join(names, ","); act != c.exp {
+						t.Fatalf("got: %v", act)
+					}
+				}
+			}
+		})
+
+		t.Run("put and delete batch", func(t *testing.T) {
+			w := NewWriter(EnableBatchWrites())
+			for i := 100; i < 130; i++ {
+				w.Put(tbl.Put1(&table2Entity{i, 2}))
+			}
+
+			w.Delete(tbl.Del1(100)).Put(tbl.Put1(&table2Entity{100, 2}))
+			if _, err := w.Run(ctx, ddb); err != nil {
+				t.Fatalf("got: %v", err)
+			}
+
+			rd := NewReader(EnableBatchReads())
+			for i := 100; i < 130; i++ {
+				rd.Get(tbl.Get1(i))
+			}
+
+			r, err := rd.Run(ctx, ddb)
+			if err != nil {
+				t.Fatalf("got: %v", err)
+			}
+
+			if act := r.Len(); act != 30 {
+				t.Fatalf("got: %v", act)
+			}
+		})
+
 		t.Run("perform checks in transaction", func(t *testing.T) {
 			if _, err := Check(tbl.Chc1(5, 1)).Run(ctx, ddb); err != nil {
 				t.Fatalf("got: %v", err)
@@ -482,7 +592,8 @@ func TestTable2End2End(t *testing.T) {
 				t.Fatalf("got: %v", act)
 			}
 
-			if act := strings.Join(ids, ","); act != "5,7,6" {
+			// the index has no sort key, items in the same partition follow the table's key
+			if act := strings.Join(ids, ","); act != "5,6,7" {
 				t.Fatalf("got: %v", act)
 			}
 
@@ -0,0 +1,112 @@
+// Package ddblocal serves the DynamoDB JSON protocol over HTTP for an in-memory database. Any
+// unmodified DynamoDB client, including the one from the aws sdk, can be pointed at it to run
+// tests without depending on AWS or DynamoDB Local.
+package ddblocal
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/gohandle/ddb/memddb"
+)
+
+// targetPrefix is the prefix of the X-Amz-Target header of every DynamoDB request
+const targetPrefix = "DynamoDB_20120810."
+
+// Server handles DynamoDB requests by dispatching them to an in-memory database
+type Server struct {
+	db *memddb.DB
+}
+
+// New inits a server that serves the provided database. If db is nil an empty database is
+// created.
+func New(db *memddb.DB) *Server {
+	if db == nil {
+		db = memddb.New()
+	}
+
+	return &Server{db: db}
+}
+
+// DB returns the database that is served
+func (s *Server) DB() *memddb.DB { return s.db }
+
+// ServeHTTP decodes the operation input, runs it against the database and encodes the output
+// or the error in the same way DynamoDB does.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	target := r.Header.Get("X-Amz-Target")
+	if !strings.HasPrefix(target, targetPrefix) {
+		s.writeError(w, awserr.New("UnknownOperationException", "", nil))
+		return
+	}
+
+	op := reflect.ValueOf(s.db).MethodByName(strings.TrimPrefix(target, targetPrefix) + "WithContext")
+	if !op.IsValid() {
+		s.writeError(w, awserr.New("UnknownOperationException", "", nil))
+		return
+	}
+
+	in := reflect.New(op.Type().In(1).Elem())
+	if err := decode(r.Body, in.Interface()); err != nil {
+		s.writeError(w, awserr.New("SerializationException", err.Error(), nil))
+		return
+	}
+
+	res := op.Call([]reflect.Value{reflect.ValueOf(r.Context()), in})
+	if err, _ := res[1].Interface().(error); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	body, err := encode(res[0].Interface())
+	if err != nil {
+		s.writeError(w, awserr.New("InternalServerError", err.Error(), nil))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.Write(body)
+}
+
+// writeError encodes an error such that the sdk decodes it into the same (typed) error
+func (s *Server) writeError(w http.ResponseWriter, err error) {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		aerr = awserr.New("InternalServerError", err.Error(), nil)
+	}
+
+	status, ns := http.StatusBadRequest, "com.amazonaws.dynamodb.v20120810#"
+	switch aerr.Code() {
+	case "ValidationException", "SerializationException":
+		ns = "com.amazon.coral.validate#"
+	case "UnknownOperationException":
+		ns = "com.amazon.coral.service#"
+	case "InternalServerError":
+		status = http.StatusInternalServerError
+	}
+
+	// typed errors carry additional fields, such as the cancellation reasons of a transaction
+	fields := map[string]json.RawMessage{}
+	if b, berr := encode(aerr); berr == nil {
+		json.Unmarshal(b, &fields)
+	}
+
+	fields["__type"], _ = json.Marshal(ns + aerr.Code())
+	if _, ok := fields["message"]; !ok {
+		fields["message"], _ = json.Marshal(aerr.Message())
+	}
+
+	body, _ := json.Marshal(fields)
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,130 @@
+package ddblocal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+func withServer(tb testing.TB) *dynamodb.DynamoDB {
+	srv := httptest.NewServer(New(nil))
+	tb.Cleanup(srv.Close)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("eu-west-1"),
+		Endpoint:    aws.String(srv.URL),
+		Credentials: credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:  aws.Int(0),
+	})
+	if err != nil {
+		tb.Fatalf("failed to create session: %v", err)
+	}
+
+	ddb := dynamodb.New(sess)
+	if _, err = ddb.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("tbl"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("pk"), AttributeType: aws.String("B")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("pk"), KeyType: aws.String("HASH")},
+		},
+		BillingMode: aws.String("PAY_PER_REQUEST"),
+	}); err != nil {
+		tb.Fatalf("failed to create table: %v", err)
+	}
+
+	return ddb
+}
+
+func TestRoundTrip(t *testing.T) {
+	ctx, ddb := context.Background(), withServer(t)
+
+	desc, err := ddb.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("tbl")})
+	if err != nil || aws.StringValue(desc.Table.TableStatus) != "ACTIVE" ||
+		time.Since(aws.TimeValue(desc.Table.CreationDateTime)) > time.Minute {
+		t.Fatalf("got: %v %v", desc, err)
+	}
+
+	key := map[string]*dynamodb.AttributeValue{"pk": {B: []byte{0, 1, 2}}}
+	if _, err = ddb.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("tbl"),
+		Item: map[string]*dynamodb.AttributeValue{
+			"pk": key["pk"], "n": {N: aws.String("1.50")}, "ss": {SS: aws.StringSlice([]string{"a", "b"})},
+		},
+	}); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	out, err := ddb.GetItemWithContext(ctx, &dynamodb.GetItemInput{TableName: aws.String("tbl"), Key: key})
+	if err != nil || string(out.Item["pk"].B) != "\x00\x01\x02" ||
+		aws.StringValue(out.Item["n"].N) != "1.5" || len(out.Item["ss"].SS) != 2 {
+		t.Fatalf("got: %v %v", out, err)
+	}
+
+	_, err = ddb.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{{ConditionCheck: &dynamodb.ConditionCheck{
+			TableName:                           aws.String("tbl"),
+			Key:                                 key,
+			ConditionExpression:                 aws.String("attribute_not_exists(pk)"),
+			ReturnValuesOnConditionCheckFailure: aws.String("ALL_OLD"),
+		}}},
+	})
+
+	var tcerr *dynamodb.TransactionCanceledException
+	if !errors.As(err, &tcerr) || len(tcerr.CancellationReasons) != 1 ||
+		aws.StringValue(tcerr.CancellationReasons[0].Code) != "ConditionalCheckFailed" ||
+		aws.StringValue(tcerr.CancellationReasons[0].Item["n"].N) != "1.5" {
+		t.Fatalf("got: %#v", err)
+	}
+
+	_, err = ddb.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("tbl"),
+		Key:       map[string]*dynamodb.AttributeValue{"pk": {S: aws.String("a")}},
+	})
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) || aerr.Code() != "ValidationException" {
+		t.Fatalf("got: %v", err)
+	}
+
+	_, err = ddb.GetItemWithContext(ctx, &dynamodb.GetItemInput{TableName: aws.String("other"), Key: key})
+	var rnf *dynamodb.ResourceNotFoundException
+	if !errors.As(err, &rnf) {
+		t.Fatalf("got: %v", err)
+	}
+}
+
+func TestUnknownOperation(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	req.Header.Set("X-Amz-Target", "DynamoDB_20120810.RestoreTableFromBackup")
+	New(nil).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "UnknownOperationException") {
+		t.Fatalf("got: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestEncode(t *testing.T) {
+	b, err := encode(&dynamodb.GetItemOutput{Item: map[string]*dynamodb.AttributeValue{
+		"m": {M: map[string]*dynamodb.AttributeValue{}}, "b": {B: []byte("a")},
+	}})
+	if err != nil || string(b) != `{"Item":{"b":{"B":"YQ=="},"m":{"M":{}}}}` {
+		t.Fatalf("got: %s %v", b, err)
+	}
+
+	b, err = encode(&dynamodb.ConditionalCheckFailedException{Message_: aws.String("failed")})
+	if err != nil || string(b) != `{"message":"failed"}` {
+		t.Fatalf("got: %s %v", b, err)
+	}
+}
@@ -0,0 +1,81 @@
+package ddblocal
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"time"
+)
+
+// decode reads the JSON of an operation input into 'v'. The field names of the input types
+// match those of the protocol, so the standard decoding applies.
+func decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// encode returns the JSON of an operation output, or error, as DynamoDB sends it
+func encode(v interface{}) ([]byte, error) {
+	w, _ := wire(reflect.ValueOf(v))
+	return json.Marshal(w)
+}
+
+// wire converts a value of the sdk types to the shape of the protocol: fields that are not set
+// are left out, fields are named after their 'locationName' tag and times are send as epoch
+// seconds. It returns false when the value is not set.
+func wire(v reflect.Value) (interface{}, bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, false
+		}
+		return wire(v.Elem())
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return float64(t.UnixNano()) / float64(time.Second), true
+		}
+
+		m := map[string]interface{}{}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			if f.PkgPath != "" || f.Tag.Get("json") == "-" {
+				continue
+			}
+
+			name := f.Tag.Get("locationName")
+			if name == "" {
+				name = f.Name
+			}
+
+			if fv, ok := wire(v.Field(i)); ok {
+				m[name] = fv
+			}
+		}
+		return m, true
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, false
+		}
+
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes(), true
+		}
+
+		l := make([]interface{}, v.Len())
+		for i := range l {
+			l[i], _ = wire(v.Index(i))
+		}
+		return l, true
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, false
+		}
+
+		m := make(map[string]interface{}, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			m[it.Key().String()], _ = wire(it.Value())
+		}
+		return m, true
+	default:
+		return v.Interface(), true
+	}
+}
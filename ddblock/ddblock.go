@@ -0,0 +1,168 @@
+// Package ddblock implements distributed locks as leases on the items of a DynamoDB table
+package ddblock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/gohandle/ddb"
+)
+
+var (
+	// ErrLocked is returned when a lock is acquired while another owner holds an unexpired lease on it
+	ErrLocked = errors.New("lock is held by another owner")
+
+	// ErrLeaseLost is returned when a lease expired and was taken over by another owner
+	ErrLeaseLost = errors.New("lease was lost")
+)
+
+// lockItem is how a lock is stored in the table. The lease has expired when Expires, in unix
+// milliseconds, is in the past. A released lock has no owner.
+type lockItem struct {
+	PK      string `dynamodbav:"pk"`
+	Owner   string `dynamodbav:"owner,omitempty"`
+	Token   int64  `dynamodbav:"token"`
+	Expires int64  `dynamodbav:"expires"`
+}
+
+func (lockItem) Keys() (pk, sk string) { return "pk", "" }
+
+// lock is the entity that maps onto the lock item
+type lock struct {
+	Name    string
+	Owner   string
+	Token   int64
+	Expires time.Time
+}
+
+func (l lock) Item() ddb.Item {
+	return &lockItem{PK: l.Name, Owner: l.Owner, Token: l.Token, Expires: l.Expires.UnixMilli()}
+}
+
+func (l *lock) FromItem(it ddb.Item) error {
+	li := it.(*lockItem)
+	l.Name, l.Owner, l.Token, l.Expires = li.PK, li.Owner, li.Token, time.UnixMilli(li.Expires)
+	return nil
+}
+
+// CreateTableInput returns the input that creates a table for storing locks
+func CreateTableInput(table string) *dynamodb.CreateTableInput {
+	s, err := ddb.NewSchema(table, lockItem{})
+	if err != nil {
+		panic("ddblock: invalid lock schema: " + err.Error())
+	}
+
+	return s.CreateTableInput()
+}
+
+// Options configure a Locker
+type Options struct {
+	owner     string
+	heartbeat time.Duration
+	now       func() time.Time
+}
+
+// Option configures the locker
+type Option func(*Options)
+
+// Owner is an option that sets the name the locker holds its leases under. By default a random
+// name is generated for every locker.
+func Owner(name string) func(o *Options) {
+	return func(o *Options) { o.owner = name }
+}
+
+// Heartbeat is an option that sets the interval at which leases are renewed. By default leases
+// are renewed three times per ttl, a zero interval disables renewal so leases expire after their
+// ttl.
+func Heartbeat(interval time.Duration) func(o *Options) {
+	return func(o *Options) { o.heartbeat = interval }
+}
+
+// Locker acquires leases on the locks that are stored in a table
+type Locker struct {
+	ddb   ddb.Dynamo
+	table string
+	opts  Options
+}
+
+// New inits a locker for locks in 'table'
+func New(d ddb.Dynamo, table string, opts ...Option) *Locker {
+	lk := &Locker{ddb: d, table: table, opts: Options{heartbeat: -1, now: time.Now}}
+	for _, o := range opts {
+		o(&lk.opts)
+	}
+
+	if lk.opts.owner == "" {
+		var b [16]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			panic("ddblock: failed to generate owner: " + err.Error())
+		}
+
+		lk.opts.owner = hex.EncodeToString(b[:])
+	}
+
+	return lk
+}
+
+// Acquire takes a lease on the lock with the provided name that expires after ttl. It fails with
+// ErrLocked if another owner holds a lease that hasn't expired, expired leases are taken over.
+// Every lease gets a fencing token that is larger than that of any earlier lease on the lock. The
+// lease is renewed in the background until it is released.
+func (lk *Locker) Acquire(ctx context.Context, name string, ttl time.Duration) (*Lease, error) {
+	now := lk.opts.now()
+	l := &lock{Name: name, Owner: lk.opts.owner, Expires: now.Add(ttl)}
+
+	var upd dynamodb.Update
+	upd.SetTableName(lk.table)
+	r, err := ddb.Update(e.NewBuilder().
+		WithCondition(e.Or(
+			e.AttributeNotExists(e.Name("owner")),
+			e.Name("expires").LessThanEqual(e.Value(now.UnixMilli())),
+		)).
+		WithUpdate(e.
+			Set(e.Name("owner"), e.Value(l.Owner)).
+			Set(e.Name("expires"), e.Value(l.Expires.UnixMilli())).
+			Add(e.Name("token"), e.Value(1)),
+		), upd, l).
+		ReturnValues(dynamodb.ReturnValueAllNew).
+		Run(ctx, lk.ddb)
+
+	var cfe *ddb.ConditionFailedError
+	switch {
+	case errors.As(err, &cfe):
+		return nil, fmt.Errorf("failed to acquire '%s': %w", name, ErrLocked)
+	case err != nil:
+		return nil, fmt.Errorf("failed to acquire '%s': %w", name, err)
+	}
+
+	if err = ddb.UnmarshalOne(r, l); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal lock: %w", err)
+	}
+
+	lease := &Lease{
+		Name: name, Token: l.Token,
+		lk: lk, ttl: ttl, expires: l.Expires,
+		done: make(chan struct{}), stopped: make(chan struct{}),
+	}
+
+	interval := lk.opts.heartbeat
+	if interval < 0 {
+		interval = ttl / 3
+	}
+
+	var hctx context.Context
+	hctx, lease.cancel = context.WithCancel(context.Background())
+	if interval > 0 {
+		go lease.heartbeat(hctx, interval)
+	} else {
+		close(lease.stopped)
+	}
+
+	return lease, nil
+}
@@ -0,0 +1,172 @@
+package ddblock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gohandle/ddb/internal/ddbtest"
+)
+
+// clock is a manual clock for testing expiry
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func withClock(c *clock) func(o *Options) {
+	return func(o *Options) { o.now = c.Now }
+}
+
+func TestAcquireRelease(t *testing.T) {
+	ctx := context.Background()
+	ddb := ddbtest.LocalDB(t, CreateTableInput(t.Name()))
+	lk1, lk2 := New(ddb, t.Name(), Owner("a")), New(ddb, t.Name(), Owner("b"))
+
+	l1, err := lk1.Acquire(ctx, "foo", time.Minute)
+	if err != nil || l1.Token != 1 {
+		t.Fatalf("got: %v %v", l1, err)
+	}
+
+	if _, err = lk2.Acquire(ctx, "foo", time.Minute); !errors.Is(err, ErrLocked) {
+		t.Fatalf("got: %v", err)
+	}
+
+	if _, err = lk1.Acquire(ctx, "foo", time.Minute); !errors.Is(err, ErrLocked) {
+		t.Fatalf("got: %v", err)
+	}
+
+	l2, err := lk2.Acquire(ctx, "bar", time.Minute)
+	if err != nil || l2.Token != 1 {
+		t.Fatalf("got: %v %v", l2, err)
+	}
+
+	if err = l1.Release(ctx); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	select {
+	case <-l1.Done():
+	default:
+		t.Fatalf("expected lease to be done")
+	}
+
+	if l1.Err() != nil {
+		t.Fatalf("got: %v", l1.Err())
+	}
+
+	if err = l1.Release(ctx); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	l3, err := lk2.Acquire(ctx, "foo", time.Minute)
+	if err != nil || l3.Token != 2 {
+		t.Fatalf("got: %v %v", l3, err)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	ctx := context.Background()
+	ddb := ddbtest.LocalDB(t, CreateTableInput(t.Name()))
+	lk1, lk2 := New(ddb, t.Name(), Heartbeat(10*time.Millisecond)), New(ddb, t.Name())
+
+	l1, err := lk1.Acquire(ctx, "foo", 100*time.Millisecond)
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	exp := l1.Expires()
+	time.Sleep(250 * time.Millisecond)
+	if !l1.Expires().After(exp) {
+		t.Fatalf("expected lease to be renewed, got: %v", l1.Expires())
+	}
+
+	if _, err = lk2.Acquire(ctx, "foo", time.Minute); !errors.Is(err, ErrLocked) {
+		t.Fatalf("got: %v", err)
+	}
+
+	if err = l1.Release(ctx); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+}
+
+func TestStealExpired(t *testing.T) {
+	ctx := context.Background()
+	ddb := ddbtest.LocalDB(t, CreateTableInput(t.Name()))
+
+	c := &clock{now: time.Now()}
+	lk1 := New(ddb, t.Name(), Owner("a"), Heartbeat(0), withClock(c))
+	lk2 := New(ddb, t.Name(), Owner("b"), Heartbeat(0), withClock(c))
+
+	l1, err := lk1.Acquire(ctx, "foo", time.Minute)
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	c.Add(time.Minute - time.Second)
+	if _, err = lk2.Acquire(ctx, "foo", time.Minute); !errors.Is(err, ErrLocked) {
+		t.Fatalf("got: %v", err)
+	}
+
+	c.Add(time.Second)
+	l2, err := lk2.Acquire(ctx, "foo", time.Minute)
+	if err != nil || l2.Token != l1.Token+1 {
+		t.Fatalf("got: %v %v", l2, err)
+	}
+
+	if err = l1.Release(ctx); !errors.Is(err, ErrLeaseLost) || !errors.Is(l1.Err(), ErrLeaseLost) {
+		t.Fatalf("got: %v", err)
+	}
+
+	if err = l2.Release(ctx); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+}
+
+func TestLeaseLost(t *testing.T) {
+	ctx := context.Background()
+	ddb := ddbtest.LocalDB(t, CreateTableInput(t.Name()))
+
+	c := &clock{now: time.Now()}
+	lk1 := New(ddb, t.Name(), Heartbeat(10*time.Millisecond))
+	lk2 := New(ddb, t.Name(), withClock(c))
+
+	l1, err := lk1.Acquire(ctx, "foo", time.Minute)
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	c.Add(2 * time.Minute)
+	l2, err := lk2.Acquire(ctx, "foo", time.Minute)
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	select {
+	case <-l1.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("expected lease to be lost")
+	}
+
+	if !errors.Is(l1.Err(), ErrLeaseLost) {
+		t.Fatalf("got: %v", l1.Err())
+	}
+
+	if err = l2.Release(ctx); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package ddblock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/gohandle/ddb"
+)
+
+// Lease is held on a lock until it is released or lost. Token is the fencing token of the lease,
+// pass it along with every write to the resource that the lock protects so the resource can
+// reject writes with a token that is lower than one it has seen before.
+type Lease struct {
+	Name  string
+	Token int64
+
+	lk  *Locker
+	ttl time.Duration
+
+	mu      sync.Mutex
+	expires time.Time
+	err     error
+
+	done    chan struct{}
+	once    sync.Once
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// Done returns a channel that is closed when the lease was released or lost. Without a heartbeat
+// a lost lease is only noticed when it is released.
+func (l *Lease) Done() <-chan struct{} { return l.done }
+
+// Err returns an error that wraps ErrLeaseLost if the lease was lost, nil otherwise
+func (l *Lease) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Expires returns the time at which the lease expires unless it is renewed
+func (l *Lease) Expires() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.expires
+}
+
+// end closes the done channel, recording why if the lease was lost
+func (l *Lease) end(err error) {
+	l.once.Do(func() {
+		l.mu.Lock()
+		l.err = err
+		l.mu.Unlock()
+		close(l.done)
+	})
+}
+
+// key returns the lock entity of the lease, for use in conditional writes
+func (l *Lease) key() *lock {
+	return &lock{Name: l.Name, Owner: l.lk.opts.owner, Token: l.Token}
+}
+
+// owned returns the condition that the lock is still held by this lease
+func (l *Lease) owned() e.ConditionBuilder {
+	return e.Name("owner").Equal(e.Value(l.lk.opts.owner)).
+		And(e.Name("token").Equal(e.Value(l.Token)))
+}
+
+// heartbeat renews the lease at every interval. The lease is lost when the lock was taken over,
+// or when it couldn't be renewed before it expired.
+func (l *Lease) heartbeat(ctx context.Context, interval time.Duration) {
+	defer close(l.stopped)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		err := l.renew(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case errors.Is(err, ErrLeaseLost):
+			l.end(err)
+			return
+		case err != nil && !l.lk.opts.now().Before(l.Expires()):
+			l.end(fmt.Errorf("%w: failed to renew before expiry: %v", ErrLeaseLost, err))
+			return
+		}
+	}
+}
+
+// renew extends the lease by its ttl. Each attempt is given until the lease expires.
+func (l *Lease) renew(ctx context.Context) error {
+	ctx, cancel := context.WithDeadline(ctx, l.Expires())
+	defer cancel()
+
+	expires := l.lk.opts.now().Add(l.ttl)
+
+	var upd dynamodb.Update
+	upd.SetTableName(l.lk.table)
+	_, err := ddb.Update(e.NewBuilder().
+		WithCondition(l.owned()).
+		WithUpdate(e.Set(e.Name("expires"), e.Value(expires.UnixMilli()))),
+		upd, l.key()).
+		Run(ctx, l.lk.ddb)
+
+	var cfe *ddb.ConditionFailedError
+	switch {
+	case errors.As(err, &cfe):
+		return fmt.Errorf("failed to renew '%s': %w", l.Name, ErrLeaseLost)
+	case err != nil:
+		return fmt.Errorf("failed to renew '%s': %w", l.Name, err)
+	}
+
+	l.mu.Lock()
+	l.expires = expires
+	l.mu.Unlock()
+	return nil
+}
+
+// Release stops renewing the lease and releases the lock so it can be acquired right away. It
+// returns an error that wraps ErrLeaseLost if the lock was taken over in the meantime. Releasing
+// a lease again returns the outcome of the first release.
+func (l *Lease) Release(ctx context.Context) error {
+	select {
+	case <-l.done:
+		return l.Err()
+	default:
+	}
+
+	l.cancel()
+	<-l.stopped
+	if err := l.Err(); err != nil {
+		return err
+	}
+
+	var upd dynamodb.Update
+	upd.SetTableName(l.lk.table)
+	_, err := ddb.Update(e.NewBuilder().
+		WithCondition(l.owned()).
+		WithUpdate(e.Remove(e.Name("owner")).Set(e.Name("expires"), e.Value(0))),
+		upd, l.key()).
+		Run(ctx, l.lk.ddb)
+
+	var cfe *ddb.ConditionFailedError
+	switch {
+	case errors.As(err, &cfe):
+		err = fmt.Errorf("failed to release '%s': %w", l.Name, ErrLeaseLost)
+		l.end(err)
+		return err
+	case err != nil:
+		return fmt.Errorf("failed to release '%s': %w", l.Name, err)
+	}
+
+	l.end(nil)
+	return nil
+}
@@ -0,0 +1,54 @@
+// Package ddbotel exports the observations of an instrumented Dynamo as OpenTelemetry spans. It is
+// a separate module such that users of ddb don't depend on OpenTelemetry. It requires a published
+// version of ddb, the go.work file in this directory builds it against the parent directory.
+package ddbotel
+
+import (
+	"context"
+
+	"github.com/gohandle/ddb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Sink returns a ddb.Sink that records every observation as a client span, started as a child
+// of the span in the context that was passed to the operation.
+func Sink(tracer trace.Tracer) ddb.Sink {
+	return ddb.SinkFunc(func(ctx context.Context, o ddb.Observation) {
+		attrs := []attribute.KeyValue{
+			semconv.DBSystemNameAWSDynamoDB,
+			semconv.DBOperationName(o.Op),
+			semconv.AWSDynamoDBTableNames(o.Table),
+			attribute.Int("aws.dynamodb.items", o.Items),
+		}
+
+		if o.Index != "" {
+			attrs = append(attrs, semconv.AWSDynamoDBIndexName(o.Index))
+		}
+
+		if o.Page > 0 {
+			attrs = append(attrs,
+				attribute.Int("aws.dynamodb.page", o.Page),
+				attribute.Bool("aws.dynamodb.more_pages", o.MorePages))
+		}
+
+		if o.ConsumedCapacity != nil {
+			attrs = append(attrs, attribute.Float64("aws.dynamodb.consumed_capacity", o.CapacityUnits))
+		}
+
+		_, span := tracer.Start(ctx, "DynamoDB."+o.Op,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithTimestamp(o.Start),
+			trace.WithAttributes(attrs...))
+
+		if o.Err != nil {
+			span.SetAttributes(semconv.ErrorTypeKey.String(o.ErrClass))
+			span.RecordError(o.Err, trace.WithTimestamp(o.Start.Add(o.Duration)))
+			span.SetStatus(codes.Error, o.ErrClass)
+		}
+
+		span.End(trace.WithTimestamp(o.Start.Add(o.Duration)))
+	})
+}
@@ -0,0 +1,59 @@
+package ddbotel
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gohandle/ddb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestSink(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	sink := Sink(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)).Tracer("ddb"))
+
+	start := time.Now().Add(-time.Second)
+	err := errors.New("foo")
+	sink.Observe(context.Background(), ddb.Observation{
+		Op: "Query", Table: "tbl", Index: "gsi1", Start: start, Duration: time.Second,
+		Err: err, ErrClass: ddb.ErrorClass(err), Page: 2,
+	})
+
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got: %v", spans)
+	}
+
+	span := spans[0]
+	if span.Name() != "DynamoDB.Query" || span.SpanKind() != trace.SpanKindClient ||
+		!span.StartTime().Equal(start) || span.Status().Code != codes.Error {
+		t.Fatalf("got: %v", span)
+	}
+
+	if end := span.EndTime(); !end.Equal(start.Add(time.Second)) {
+		t.Fatalf("got: %v", end)
+	}
+
+	attrs := attribute.NewSet(span.Attributes()...)
+	if v, _ := attrs.Value("aws.dynamodb.table_names"); len(v.AsStringSlice()) != 1 || v.AsStringSlice()[0] != "tbl" {
+		t.Fatalf("got: %v", v)
+	}
+
+	if v, _ := attrs.Value("aws.dynamodb.index_name"); v.AsString() != "gsi1" {
+		t.Fatalf("got: %v", v)
+	}
+
+	if v, _ := attrs.Value("aws.dynamodb.page"); v.AsInt64() != 2 {
+		t.Fatalf("got: %v", v)
+	}
+
+	if v, _ := attrs.Value("db.operation.name"); v.AsString() != "Query" {
+		t.Fatalf("got: %v", v)
+	}
+}
@@ -0,0 +1,166 @@
+// Package ddbqueue implements an at-least-once job queue on a DynamoDB table. Jobs are ordinary
+// entities whose item embeds Meta, which holds the queue they are on and when they are ready.
+package ddbqueue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/gohandle/ddb"
+)
+
+// IndexName is the name of the global index that sorts the jobs of each queue by ready time
+const IndexName = "queue-ready"
+
+// DeadLetterSuffix is appended to the name of a queue to name its dead-letter queue
+const DeadLetterSuffix = "#dead"
+
+// Meta holds the queue attributes of a job. It must be embedded in the item of every job. It
+// declares the index that the queue needs, such that the table can be created from the item.
+type Meta struct {
+	Queue    string `dynamodbav:"queue"`
+	Ready    int64  `dynamodbav:"ready"`
+	Attempts int    `dynamodbav:"attempts"`
+	Receipt  string `dynamodbav:"receipt,omitempty"`
+}
+
+func (m *Meta) queueMeta() *Meta { return m }
+
+// Indexes declares the index of the queue
+func (Meta) Indexes() []ddb.Index {
+	return []ddb.Index{{Name: IndexName, PK: "queue", SK: "ready"}}
+}
+
+// metaOf returns the queue attributes of a job item
+func metaOf(it ddb.Item) (*Meta, error) {
+	mi, ok := it.(interface{ queueMeta() *Meta })
+	if !ok {
+		return nil, fmt.Errorf("item of type %T doesn't embed ddbqueue.Meta", it)
+	}
+
+	return mi.queueMeta(), nil
+}
+
+// Job is an entity that can be put on the queue
+type Job interface {
+	ddb.Itemizer
+	ddb.Deitemizer
+}
+
+// prepared turns an item back into an itemizer, so an item with its queue attributes set can be
+// passed to a write
+type prepared struct{ it ddb.Item }
+
+func (p prepared) Item() ddb.Item { return p.it }
+
+// Options configure a queue
+type Options struct {
+	visibility  time.Duration
+	maxAttempts int
+	batch       int
+	now         func() time.Time
+}
+
+// Option configures the queue
+type Option func(*Options)
+
+// Visibility is an option that sets for how long a dequeued job is hidden from other consumers.
+// If it isn't acked or nacked within that time it is delivered again. Defaults to 30 seconds.
+func Visibility(d time.Duration) func(o *Options) {
+	return func(o *Options) { o.visibility = d }
+}
+
+// MaxAttempts is an option that moves jobs that have been delivered n times to the dead-letter
+// queue instead of delivering them again. By default jobs are retried indefinitely.
+func MaxAttempts(n int) func(o *Options) {
+	return func(o *Options) { o.maxAttempts = n }
+}
+
+// BatchSize is an option that sets the nr of candidate jobs that Dequeue reads per request. More
+// are read when other consumers claimed all of them first. Defaults to 10.
+func BatchSize(n int) func(o *Options) {
+	return func(o *Options) { o.batch = n }
+}
+
+// Queue holds the jobs with the same queue name in a table
+type Queue struct {
+	ddb   ddb.Dynamo
+	table string
+	name  string
+	opts  Options
+}
+
+// New inits the queue with the provided name, its jobs are stored in 'table'
+func New(d ddb.Dynamo, table, name string, opts ...Option) *Queue {
+	q := &Queue{ddb: d, table: table, name: name, opts: Options{
+		visibility: 30 * time.Second,
+		batch:      10,
+		now:        time.Now,
+	}}
+
+	for _, o := range opts {
+		o(&q.opts)
+	}
+
+	return q
+}
+
+// DeadLetters returns the queue that holds the jobs that failed too many times. Jobs on it are
+// never dead-lettered again.
+func (q *Queue) DeadLetters() *Queue {
+	dq := *q
+	dq.name, dq.opts.maxAttempts = q.name+DeadLetterSuffix, 0
+	return &dq
+}
+
+// Enqueue puts the job on the queue, it is ready to be dequeued after the delay. A job that is
+// enqueued again with the same key is replaced and its attempts are reset.
+func (q *Queue) Enqueue(ctx context.Context, job ddb.Itemizer, delay time.Duration) error {
+	it := job.Item()
+	m, err := metaOf(it)
+	if err != nil {
+		return err
+	}
+
+	*m = Meta{Queue: q.name, Ready: q.opts.now().Add(delay).UnixMilli()}
+
+	var put dynamodb.Put
+	put.SetTableName(q.table)
+	if _, err = ddb.Put(e.Builder{}, put, prepared{it}).Run(ctx, q.ddb); err != nil {
+		return fmt.Errorf("failed to enqueue: %w", err)
+	}
+
+	return nil
+}
+
+// Depth returns the nr of jobs that are ready to be dequeued
+func (q *Queue) Depth(ctx context.Context) (int64, error) {
+	in := q.readyQuery()
+	in.SetSelect(dynamodb.SelectCount)
+
+	r, err := ddb.Query(e.NewBuilder().WithKeyCondition(q.readyCondition()), in).Run(ctx, q.ddb)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count jobs: %w", err)
+	}
+
+	for r.Next() {
+	}
+
+	return r.Len(), r.Err()
+}
+
+// readyQuery returns the query input for the index of the queue
+func (q *Queue) readyQuery() (in dynamodb.QueryInput) {
+	in.SetTableName(q.table)
+	in.SetIndexName(IndexName)
+	return
+}
+
+// readyCondition returns the key condition that selects the jobs that are ready
+func (q *Queue) readyCondition() e.KeyConditionBuilder {
+	return e.Key("queue").Equal(e.Value(q.name)).
+		And(e.Key("ready").LessThanEqual(e.Value(q.opts.now().UnixMilli())))
+}
@@ -0,0 +1,193 @@
+package ddbqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gohandle/ddb"
+	"github.com/gohandle/ddb/internal/ddbtest"
+)
+
+type emailItem struct {
+	PK string `dynamodbav:"pk"`
+	To string `dynamodbav:"to"`
+	Meta
+}
+
+func (emailItem) Keys() (pk, sk string) { return "pk", "" }
+
+type email struct {
+	ID string
+	To string
+}
+
+func (m email) Item() ddb.Item { return &emailItem{PK: "email#" + m.ID, To: m.To} }
+
+func (m *email) FromItem(it ddb.Item) error {
+	m.ID, m.To = it.(*emailItem).PK[len("email#"):], it.(*emailItem).To
+	return nil
+}
+
+type plainItem struct {
+	PK string `dynamodbav:"pk"`
+}
+
+func (plainItem) Keys() (pk, sk string) { return "pk", "" }
+
+type plain struct{}
+
+func (plain) Item() ddb.Item { return &plainItem{PK: "p"} }
+
+func withClock(now *time.Time) func(o *Options) {
+	return func(o *Options) { o.now = func() time.Time { return *now } }
+}
+
+func TestQueue(t *testing.T) {
+	ctx, tbl := context.Background(), t.Name()
+	s, err := ddb.NewSchema(tbl, &emailItem{})
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	mddb := ddbtest.MemDB(t, s.CreateTableInput())
+
+	now := time.Now()
+	q := New(mddb, tbl, "emails", MaxAttempts(3), withClock(&now))
+
+	dequeue := func(t *testing.T, exp string, attempts int) *Delivery {
+		var m email
+		d, err := q.Dequeue(ctx, &m)
+		if err != nil || d == nil || m.ID != exp || d.Attempts != attempts {
+			t.Fatalf("got: %v %v %v", d, m, err)
+		}
+		return d
+	}
+
+	depth := func(t *testing.T, q *Queue, exp int64) {
+		if n, err := q.Depth(ctx); err != nil || n != exp {
+			t.Fatalf("got: %v %v", n, err)
+		}
+	}
+
+	for i, id := range []string{"a", "b"} {
+		if err := q.Enqueue(ctx, email{ID: id, To: id + "@example.com"}, time.Duration(i)*time.Millisecond); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+	}
+
+	if err := q.Enqueue(ctx, email{ID: "c"}, time.Minute); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if err := q.Enqueue(ctx, plain{}, 0); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	now = now.Add(time.Millisecond)
+	depth(t, q, 2)
+
+	da := dequeue(t, "a", 1)
+	db := dequeue(t, "b", 1)
+	depth(t, q, 0)
+
+	if d, err := q.Dequeue(ctx, &email{}); d != nil || err != nil {
+		t.Fatalf("got: %v %v", d, err)
+	}
+
+	t.Run("ack", func(t *testing.T) {
+		if err := q.Ack(ctx, da); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		if err := q.Ack(ctx, da); !errors.Is(err, ErrNotClaimed) {
+			t.Fatalf("got: %v", err)
+		}
+
+		if err := q.Ack(ctx, nil); !errors.Is(err, ErrNoDelivery) {
+			t.Fatalf("got: %v", err)
+		}
+
+		if err := q.Nack(ctx, nil, 0); !errors.Is(err, ErrNoDelivery) {
+			t.Fatalf("got: %v", err)
+		}
+	})
+
+	t.Run("nack", func(t *testing.T) {
+		if err := q.Nack(ctx, db, 0); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		db = dequeue(t, "b", 2)
+	})
+
+	t.Run("visibility timeout", func(t *testing.T) {
+		now = now.Add(30 * time.Second)
+		dequeue(t, "b", 3)
+
+		if err := q.Ack(ctx, db); !errors.Is(err, ErrNotClaimed) {
+			t.Fatalf("got: %v", err)
+		}
+	})
+
+	t.Run("dead letter", func(t *testing.T) {
+		now = now.Add(time.Minute)
+		dequeue(t, "c", 1)
+		depth(t, q, 1)
+
+		// b was delivered too often and is dead-lettered instead
+		if d, err := q.Dequeue(ctx, &email{}); d != nil || err != nil {
+			t.Fatalf("got: %v %v", d, err)
+		}
+
+		depth(t, q, 0)
+
+		dq := q.DeadLetters()
+		depth(t, dq, 1)
+
+		var m email
+		d, err := dq.Dequeue(ctx, &m)
+		if err != nil || m.ID != "b" || m.To != "b@example.com" || d.Attempts != 4 {
+			t.Fatalf("got: %v %v %v", d, m, err)
+		}
+
+		if err = dq.Ack(ctx, d); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+	})
+}
+
+func TestDequeuePages(t *testing.T) {
+	ctx, tbl := context.Background(), t.Name()
+	s, _ := ddb.NewSchema(tbl, &emailItem{})
+	mddb := ddbtest.MemDB(t, s.CreateTableInput())
+
+	now := time.Now()
+	q := New(mddb, tbl, "emails", MaxAttempts(1), BatchSize(1), withClock(&now))
+
+	for i, id := range []string{"a", "b", "c"} {
+		if err := q.Enqueue(ctx, email{ID: id}, time.Duration(i/2)*40*time.Second); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+	}
+
+	for _, id := range []string{"a", "b"} {
+		var m email
+		if d, err := q.Dequeue(ctx, &m); err != nil || d == nil || m.ID != id {
+			t.Fatalf("got: %v %v %v", d, m, err)
+		}
+	}
+
+	// the claims of a and b expire before c is ready, they are dead-lettered and c is on a later page
+	now = now.Add(time.Minute)
+
+	var m email
+	if d, err := q.Dequeue(ctx, &m); err != nil || d == nil || m.ID != "c" {
+		t.Fatalf("got: %v %v %v", d, m, err)
+	}
+
+	if d, err := q.Dequeue(ctx, &m); d != nil || err != nil {
+		t.Fatalf("got: %v %v", d, err)
+	}
+}
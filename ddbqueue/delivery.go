@@ -0,0 +1,208 @@
+package ddbqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/gohandle/ddb"
+)
+
+// ErrNoDelivery is returned when a nil delivery is acked or nacked, Dequeue returns nil when no
+// job was ready.
+var ErrNoDelivery = errors.New("no delivery")
+
+// ErrNotClaimed is returned when a delivery is acked or nacked after its visibility timeout
+// expired and the job was claimed again, or removed.
+var ErrNotClaimed = errors.New("job is no longer claimed by the delivery")
+
+// Delivery is a claim on a dequeued job. The claim lasts for the visibility timeout of the queue.
+type Delivery struct {
+	// Attempts is the nr of times the job has been delivered, including this delivery
+	Attempts int
+
+	item    ddb.Item
+	receipt string
+}
+
+// capture scans a job while holding on to its item, so its queue attributes can be read
+type capture struct {
+	job  Job
+	item ddb.Item
+	meta *Meta
+}
+
+func (c *capture) Item() ddb.Item { return c.job.Item() }
+
+func (c *capture) FromItem(it ddb.Item) (err error) {
+	if c.meta, err = metaOf(it); err != nil {
+		return err
+	}
+
+	c.item = it
+	return c.job.FromItem(it)
+}
+
+// Dequeue claims the job that has been ready the longest and scans it into 'job'. It returns nil
+// if no job is ready. Jobs that have been delivered too often are moved to the dead-letter queue
+// instead. The job must be acked when it has been handled, or nacked to retry it. The contents
+// of 'job' are only valid when a delivery is returned.
+func (q *Queue) Dequeue(ctx context.Context, job Job) (*Delivery, error) {
+	in := q.readyQuery()
+	in.SetLimit(int64(q.opts.batch))
+
+	r, err := ddb.Query(e.NewBuilder().WithKeyCondition(q.readyCondition()), in).Run(ctx, q.ddb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query ready jobs: %w", err)
+	}
+
+	// other consumers may claim the same jobs, so we try each candidate in turn. Further pages
+	// are read until a claim succeeds or no ready jobs are left.
+	for r.Next() {
+		c := &capture{job: job}
+		if err = r.Scan(c); err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+
+		d, err := q.claim(ctx, job, c)
+		switch {
+		case err != nil:
+			return nil, err
+		case d != nil:
+			return d, nil
+		}
+	}
+
+	return nil, r.Err()
+}
+
+// claim hides a candidate job for the visibility timeout. It returns nil if another consumer
+// claimed the job first or if the job was dead-lettered. Jobs that have been delivered the
+// maximum nr of times are dead-lettered without being delivered again.
+func (q *Queue) claim(ctx context.Context, job Job, c *capture) (*Delivery, error) {
+	var cfe *ddb.ConditionFailedError
+	if q.opts.maxAttempts > 0 && c.meta.Attempts >= q.opts.maxAttempts {
+		err := q.move(ctx, c.item, q.unchanged(c), q.name+DeadLetterSuffix, 0)
+		if err != nil && !errors.As(err, &cfe) {
+			return nil, fmt.Errorf("failed to dead-letter job: %w", err)
+		}
+
+		return nil, nil
+	}
+
+	now, receipt := q.opts.now(), newReceipt()
+
+	var upd dynamodb.Update
+	upd.SetTableName(q.table)
+	r, err := ddb.Update(e.NewBuilder().
+		WithCondition(q.unchanged(c)).
+		WithUpdate(e.
+			Set(e.Name("ready"), e.Value(now.Add(q.opts.visibility).UnixMilli())).
+			Set(e.Name("receipt"), e.Value(receipt)).
+			Add(e.Name("attempts"), e.Value(1))),
+		upd, prepared{c.item}).
+		ReturnValues(dynamodb.ReturnValueAllNew).
+		Run(ctx, q.ddb)
+
+	switch {
+	case errors.As(err, &cfe):
+		return nil, nil
+	case err != nil:
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+
+	claimed := &capture{job: job}
+	if err = ddb.UnmarshalOne(r, claimed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
+	}
+
+	return &Delivery{Attempts: claimed.meta.Attempts, item: claimed.item, receipt: receipt}, nil
+}
+
+// Ack removes the job from the queue after it has been handled
+func (q *Queue) Ack(ctx context.Context, d *Delivery) error {
+	if d == nil {
+		return fmt.Errorf("failed to ack job: %w", ErrNoDelivery)
+	}
+
+	var del dynamodb.Delete
+	del.SetTableName(q.table)
+	_, err := ddb.Delete(e.NewBuilder().WithCondition(q.claimed(d)), del, prepared{d.item}).Run(ctx, q.ddb)
+
+	var cfe *ddb.ConditionFailedError
+	switch {
+	case errors.As(err, &cfe):
+		return fmt.Errorf("failed to ack job: %w", ErrNotClaimed)
+	case err != nil:
+		return fmt.Errorf("failed to ack job: %w", err)
+	}
+
+	return nil
+}
+
+// Nack gives up the claim on the job so it is delivered again after the delay
+func (q *Queue) Nack(ctx context.Context, d *Delivery, delay time.Duration) error {
+	if d == nil {
+		return fmt.Errorf("failed to nack job: %w", ErrNoDelivery)
+	}
+
+	err := q.move(ctx, d.item, q.claimed(d), q.name, delay)
+
+	var cfe *ddb.ConditionFailedError
+	switch {
+	case errors.As(err, &cfe):
+		return fmt.Errorf("failed to nack job: %w", ErrNotClaimed)
+	case err != nil:
+		return fmt.Errorf("failed to nack job: %w", err)
+	}
+
+	return nil
+}
+
+// move puts a job on queue 'name', where it is ready after the delay, if the condition holds.
+// Any claim on the job is given up.
+func (q *Queue) move(
+	ctx context.Context,
+	it ddb.Item,
+	cond e.ConditionBuilder,
+	name string,
+	delay time.Duration,
+) error {
+	var upd dynamodb.Update
+	upd.SetTableName(q.table)
+	_, err := ddb.Update(e.NewBuilder().
+		WithCondition(cond).
+		WithUpdate(e.
+			Set(e.Name("queue"), e.Value(name)).
+			Set(e.Name("ready"), e.Value(q.opts.now().Add(delay).UnixMilli())).
+			Remove(e.Name("receipt"))),
+		upd, prepared{it}).
+		Run(ctx, q.ddb)
+
+	return err
+}
+
+// unchanged returns the condition that a candidate job is still on the queue as it was read
+func (q *Queue) unchanged(c *capture) e.ConditionBuilder {
+	return e.Name("queue").Equal(e.Value(q.name)).And(e.Name("ready").Equal(e.Value(c.meta.Ready)))
+}
+
+// claimed returns the condition that the job is still claimed by the delivery
+func (q *Queue) claimed(d *Delivery) e.ConditionBuilder {
+	return e.Name("receipt").Equal(e.Value(d.receipt))
+}
+
+// newReceipt generates a random receipt that identifies a delivery
+func newReceipt() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("ddbqueue: failed to generate receipt: " + err.Error())
+	}
+
+	return hex.EncodeToString(b[:])
+}
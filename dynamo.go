@@ -55,6 +55,18 @@ type Dynamo interface {
 		*dynamodb.ScanInput,
 		...request.Option,
 	) (*dynamodb.ScanOutput, error)
+
+	BatchGetItemWithContext(
+		aws.Context,
+		*dynamodb.BatchGetItemInput,
+		...request.Option,
+	) (*dynamodb.BatchGetItemOutput, error)
+
+	BatchWriteItemWithContext(
+		aws.Context,
+		*dynamodb.BatchWriteItemInput,
+		...request.Option,
+	) (*dynamodb.BatchWriteItemOutput, error)
 }
 
 // Logger interface can be implemented to log all interaction with DynamoDB
@@ -144,6 +156,24 @@ func (lddb *loggedDynamo) ScanWithContext(
 	return lddb.ddb.ScanWithContext(ctx, in, opts...)
 }
 
+func (lddb *loggedDynamo) BatchGetItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.BatchGetItemInput,
+	opts ...request.Option,
+) (*dynamodb.BatchGetItemOutput, error) {
+	lddb.logf(in)
+	return lddb.ddb.BatchGetItemWithContext(ctx, in, opts...)
+}
+
+func (lddb *loggedDynamo) BatchWriteItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.BatchWriteItemInput,
+	opts ...request.Option,
+) (*dynamodb.BatchWriteItemOutput, error) {
+	lddb.logf(in)
+	return lddb.ddb.BatchWriteItemWithContext(ctx, in, opts...)
+}
+
 // LoggedDynamo returns a dynamo interface that logs every interaction with dynamodb to the
 // provider logger
 func LoggedDynamo(ddb Dynamo, logs Logger) Dynamo {
@@ -0,0 +1,155 @@
+package ddb
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Cancellation reason codes that DynamoDB reports for each operation of a canceled transaction
+const (
+	TxReasonNone                            = "None"
+	TxReasonConditionalCheckFailed          = "ConditionalCheckFailed"
+	TxReasonItemCollectionSizeLimitExceeded = "ItemCollectionSizeLimitExceeded"
+	TxReasonTransactionConflict             = "TransactionConflict"
+	TxReasonProvisionedThroughputExceeded   = "ProvisionedThroughputExceeded"
+	TxReasonThrottlingError                 = "ThrottlingError"
+	TxReasonValidationError                 = "ValidationError"
+)
+
+const (
+	// TxLimitOperations names the limit on the nr of operations in a transaction
+	TxLimitOperations = "operations"
+
+	// TxLimitSize names the limit on the aggregate size of a transaction
+	TxLimitSize = "size"
+)
+
+// TxLimitError is returned when a write would exceed one of the limits DynamoDB imposes on
+// a single transaction. It is returned before anything is send to DynamoDB.
+type TxLimitError struct {
+	Limit  string
+	Max    int
+	Actual int
+}
+
+func (e *TxLimitError) Error() string {
+	return fmt.Sprintf("transaction exceeds the %s limit: %d > %d", e.Limit, e.Actual, e.Max)
+}
+
+// TxChunkError is returned when a write that was split into several transactions failed
+// part-way through. The chunks before Failed have been committed, Failed and the chunks after
+// it have not. Chunks holds the nr of operations in each chunk, in the order they were added.
+type TxChunkError struct {
+	Chunks []int
+	Failed int
+	Err    error
+}
+
+func (e *TxChunkError) Error() string {
+	return fmt.Sprintf("failed to transact chunk %d of %d, %d operation(s) were committed: %v",
+		e.Failed+1, len(e.Chunks), e.Committed(), e.Err)
+}
+
+func (e *TxChunkError) Unwrap() error { return e.Err }
+
+// Committed returns the nr of operations that have been committed, counted from the first
+// operation that was added to the writer.
+func (e *TxChunkError) Committed() (n int) {
+	for _, c := range e.Chunks[:e.Failed] {
+		n += c
+	}
+	return
+}
+
+// ConditionFailedError is returned when a write failed because the condition of one of its
+// operations evaluated to false. Op is the index of that operation in the order it was added
+// to the writer. If the operation asked for it with ReturnValuesOnConditionCheckFailure the
+// item, as it was at the time of the failure, is available through Item. For transactions the
+// error wraps a TxCanceledError with the reasons for all operations.
+type ConditionFailedError struct {
+	Op   int
+	Item map[string]*dynamodb.AttributeValue
+	Err  error
+}
+
+func (e *ConditionFailedError) Error() string {
+	return fmt.Sprintf("condition of operation %d failed: %v", e.Op, e.Err)
+}
+
+func (e *ConditionFailedError) Unwrap() error { return e.Err }
+
+// Result returns the item that failed the condition as a result so it can be scanned into an
+// entity. The result is empty if the item was not returned.
+func (e *ConditionFailedError) Result() Result {
+	if e.Item == nil {
+		return emptyResult{}
+	}
+
+	return newResult(e.Item)
+}
+
+// TxCancelReason describes why DynamoDB canceled a transaction for one of its operations
+type TxCancelReason struct {
+	Op      int
+	Code    string
+	Message string
+	Key     map[string]*dynamodb.AttributeValue
+	Item    map[string]*dynamodb.AttributeValue
+}
+
+// TxCanceledError is returned when DynamoDB canceled a transaction. It holds a reason for
+// every operation in the transaction, in the order the operations were added to the writer.
+// Operations that didn't cause the cancellation have the TxReasonNone code.
+type TxCanceledError struct {
+	Reasons []TxCancelReason
+	Err     error
+}
+
+func (e *TxCanceledError) Error() string {
+	var codes []string
+	for _, r := range e.Reasons {
+		if r.Code != TxReasonNone {
+			codes = append(codes, fmt.Sprintf("%d:%s", r.Op, r.Code))
+		}
+	}
+
+	return fmt.Sprintf("transaction canceled [%s]: %v", strings.Join(codes, ", "), e.Err)
+}
+
+func (e *TxCanceledError) Unwrap() error { return e.Err }
+
+// Has returns whether any of the operations was canceled for the reason with the provided code
+func (e *TxCanceledError) Has(code string) bool {
+	for _, r := range e.Reasons {
+		if r.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// IdempotencyMismatchError is returned when a transaction was send with a ClientRequestToken
+// that was used before, within the idempotency window, for a transaction with different items.
+type IdempotencyMismatchError struct {
+	Token string
+	Err   error
+}
+
+func (e *IdempotencyMismatchError) Error() string {
+	return fmt.Sprintf("token %q was used before for a different transaction: %v", e.Token, e.Err)
+}
+
+func (e *IdempotencyMismatchError) Unwrap() error { return e.Err }
+
+// SchemaMismatchError is returned when an existing table doesn't match the schema that is
+// derived from its item type. Diffs describes each difference.
+type SchemaMismatchError struct {
+	Table string
+	Diffs []string
+}
+
+func (e *SchemaMismatchError) Error() string {
+	return fmt.Sprintf("table '%s' doesn't match its schema: %s", e.Table, strings.Join(e.Diffs, "; "))
+}
@@ -1,5 +1,7 @@
 module github.com/gohandle/ddb
 
-go 1.15
+go 1.23
 
 require github.com/aws/aws-sdk-go v1.35.35
+
+require github.com/jmespath/go-jmespath v0.4.0 // indirect
@@ -0,0 +1,356 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// Observation describes a single call to DynamoDB as recorded by InstrumentedDynamo
+type Observation struct {
+	// Op is the name of the DynamoDB operation, for example: "Query"
+	Op string
+
+	// Table is the name of the table, operations on multiple tables have the sorted names
+	// joined with a comma.
+	Table string
+
+	// Index is the name of the index that was queried or scanned, if any
+	Index string
+
+	// Start is when the operation was started and Duration how long it took
+	Start    time.Time
+	Duration time.Duration
+
+	// Err is the error returned by the operation and ErrClass its classification, as returned
+	// by ErrorClass. Both are empty if the operation succeeded.
+	Err      error
+	ErrClass string
+
+	// Items is the nr of items that were read or written
+	Items int
+
+	// Page is the (1-based) page nr when the operation fetched a page of a Query or Scan
+	// result, MorePages reports whether more pages were available.
+	Page      int
+	MorePages bool
+
+	// ConsumedCapacity is returned by DynamoDB when asked for, CapacityUnits holds the total
+	ConsumedCapacity []*dynamodb.ConsumedCapacity
+	CapacityUnits    float64
+}
+
+// Sink receives observations, it must be safe for concurrent use
+type Sink interface {
+	Observe(ctx context.Context, o Observation)
+}
+
+// SinkFunc allows a function to be used as a Sink
+type SinkFunc func(ctx context.Context, o Observation)
+
+// Observe calls f
+func (f SinkFunc) Observe(ctx context.Context, o Observation) { f(ctx, o) }
+
+// MultiSink sends observations to each of the sinks
+func MultiSink(sinks ...Sink) Sink {
+	return SinkFunc(func(ctx context.Context, o Observation) {
+		for _, s := range sinks {
+			s.Observe(ctx, o)
+		}
+	})
+}
+
+// ErrorClass returns a short, low-cardinality, classification of an error returned by DynamoDB.
+// This is the error code for aws errors, "Canceled" or "DeadlineExceeded" for context errors and
+// "Unknown" for anything else. It returns an empty string for nil.
+func ErrorClass(err error) string {
+	var aerr awserr.Error
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return "Canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "DeadlineExceeded"
+	case errors.As(err, &aerr):
+		if aerr.Code() == request.CanceledErrorCode {
+			return "Canceled"
+		}
+		return aerr.Code()
+	}
+
+	return "Unknown"
+}
+
+// pageKey is the context key that holds the page nr of a paginated result
+type pageKey struct{}
+
+// withPage returns a context that tells InstrumentedDynamo which page is being fetched
+func withPage(ctx context.Context, page int) context.Context {
+	return context.WithValue(ctx, pageKey{}, page)
+}
+
+// instrumentedDynamo implements the Dynamo interface but records an observation for every call
+type instrumentedDynamo struct {
+	ddb  Dynamo
+	sink Sink
+}
+
+// start inits an observation for an operation on the provided table(s)
+func (iddb *instrumentedDynamo) start(ctx context.Context, op string, tables ...string) *Observation {
+	sort.Strings(tables)
+	o := &Observation{Op: op, Table: strings.Join(tables, ","), Start: time.Now()}
+	o.Page, _ = ctx.Value(pageKey{}).(int)
+	return o
+}
+
+// observe completes the observation and hands it to the sink
+func (iddb *instrumentedDynamo) observe(ctx context.Context, o *Observation, err error) {
+	o.Duration = time.Since(o.Start)
+	o.Err, o.ErrClass = err, ErrorClass(err)
+	for _, cc := range o.ConsumedCapacity {
+		o.CapacityUnits += aws.Float64Value(cc.CapacityUnits)
+	}
+
+	iddb.sink.Observe(ctx, *o)
+}
+
+// capacity adds consumed capacity to the observation
+func (o *Observation) capacity(ccs ...*dynamodb.ConsumedCapacity) {
+	for _, cc := range ccs {
+		if cc != nil {
+			o.ConsumedCapacity = append(o.ConsumedCapacity, cc)
+		}
+	}
+}
+
+// tableSet returns the unique table names
+func tableSet(names ...*string) (tables []string) {
+	seen := map[string]bool{}
+	for _, n := range names {
+		if !seen[aws.StringValue(n)] {
+			seen[aws.StringValue(n)] = true
+			tables = append(tables, aws.StringValue(n))
+		}
+	}
+
+	return
+}
+
+func (iddb *instrumentedDynamo) PutItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.PutItemInput,
+	opts ...request.Option,
+) (*dynamodb.PutItemOutput, error) {
+	o := iddb.start(ctx, "PutItem", aws.StringValue(in.TableName))
+	out, err := iddb.ddb.PutItemWithContext(ctx, in, opts...)
+	if err == nil {
+		o.Items = 1
+		o.capacity(out.ConsumedCapacity)
+	}
+
+	iddb.observe(ctx, o, err)
+	return out, err
+}
+
+func (iddb *instrumentedDynamo) GetItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.GetItemInput,
+	opts ...request.Option,
+) (*dynamodb.GetItemOutput, error) {
+	o := iddb.start(ctx, "GetItem", aws.StringValue(in.TableName))
+	out, err := iddb.ddb.GetItemWithContext(ctx, in, opts...)
+	if err == nil {
+		if out.Item != nil {
+			o.Items = 1
+		}
+		o.capacity(out.ConsumedCapacity)
+	}
+
+	iddb.observe(ctx, o, err)
+	return out, err
+}
+
+func (iddb *instrumentedDynamo) DeleteItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.DeleteItemInput,
+	opts ...request.Option,
+) (*dynamodb.DeleteItemOutput, error) {
+	o := iddb.start(ctx, "DeleteItem", aws.StringValue(in.TableName))
+	out, err := iddb.ddb.DeleteItemWithContext(ctx, in, opts...)
+	if err == nil {
+		o.Items = 1
+		o.capacity(out.ConsumedCapacity)
+	}
+
+	iddb.observe(ctx, o, err)
+	return out, err
+}
+
+func (iddb *instrumentedDynamo) UpdateItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.UpdateItemInput,
+	opts ...request.Option,
+) (*dynamodb.UpdateItemOutput, error) {
+	o := iddb.start(ctx, "UpdateItem", aws.StringValue(in.TableName))
+	out, err := iddb.ddb.UpdateItemWithContext(ctx, in, opts...)
+	if err == nil {
+		o.Items = 1
+		o.capacity(out.ConsumedCapacity)
+	}
+
+	iddb.observe(ctx, o, err)
+	return out, err
+}
+
+func (iddb *instrumentedDynamo) QueryWithContext(
+	ctx aws.Context,
+	in *dynamodb.QueryInput,
+	opts ...request.Option,
+) (*dynamodb.QueryOutput, error) {
+	o := iddb.start(ctx, "Query", aws.StringValue(in.TableName))
+	o.Index = aws.StringValue(in.IndexName)
+	out, err := iddb.ddb.QueryWithContext(ctx, in, opts...)
+	if err == nil {
+		o.Items = int(aws.Int64Value(out.Count))
+		o.MorePages = out.LastEvaluatedKey != nil
+		o.capacity(out.ConsumedCapacity)
+	}
+
+	iddb.observe(ctx, o, err)
+	return out, err
+}
+
+func (iddb *instrumentedDynamo) TransactWriteItemsWithContext(
+	ctx aws.Context,
+	in *dynamodb.TransactWriteItemsInput,
+	opts ...request.Option,
+) (*dynamodb.TransactWriteItemsOutput, error) {
+	var names []*string
+	for _, wi := range in.TransactItems {
+		switch {
+		case wi.Put != nil:
+			names = append(names, wi.Put.TableName)
+		case wi.Update != nil:
+			names = append(names, wi.Update.TableName)
+		case wi.Delete != nil:
+			names = append(names, wi.Delete.TableName)
+		case wi.ConditionCheck != nil:
+			names = append(names, wi.ConditionCheck.TableName)
+		}
+	}
+
+	o := iddb.start(ctx, "TransactWriteItems", tableSet(names...)...)
+	out, err := iddb.ddb.TransactWriteItemsWithContext(ctx, in, opts...)
+	if err == nil {
+		o.Items = len(in.TransactItems)
+		o.capacity(out.ConsumedCapacity...)
+	}
+
+	iddb.observe(ctx, o, err)
+	return out, err
+}
+
+func (iddb *instrumentedDynamo) TransactGetItemsWithContext(
+	ctx aws.Context,
+	in *dynamodb.TransactGetItemsInput,
+	opts ...request.Option,
+) (*dynamodb.TransactGetItemsOutput, error) {
+	var names []*string
+	for _, gi := range in.TransactItems {
+		if gi.Get != nil {
+			names = append(names, gi.Get.TableName)
+		}
+	}
+
+	o := iddb.start(ctx, "TransactGetItems", tableSet(names...)...)
+	out, err := iddb.ddb.TransactGetItemsWithContext(ctx, in, opts...)
+	if err == nil {
+		for _, resp := range out.Responses {
+			if resp.Item != nil {
+				o.Items++
+			}
+		}
+		o.capacity(out.ConsumedCapacity...)
+	}
+
+	iddb.observe(ctx, o, err)
+	return out, err
+}
+
+func (iddb *instrumentedDynamo) ScanWithContext(
+	ctx aws.Context,
+	in *dynamodb.ScanInput,
+	opts ...request.Option,
+) (*dynamodb.ScanOutput, error) {
+	o := iddb.start(ctx, "Scan", aws.StringValue(in.TableName))
+	o.Index = aws.StringValue(in.IndexName)
+	out, err := iddb.ddb.ScanWithContext(ctx, in, opts...)
+	if err == nil {
+		o.Items = int(aws.Int64Value(out.Count))
+		o.MorePages = out.LastEvaluatedKey != nil
+		o.capacity(out.ConsumedCapacity)
+	}
+
+	iddb.observe(ctx, o, err)
+	return out, err
+}
+
+func (iddb *instrumentedDynamo) BatchGetItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.BatchGetItemInput,
+	opts ...request.Option,
+) (*dynamodb.BatchGetItemOutput, error) {
+	var names []*string
+	for name := range in.RequestItems {
+		names = append(names, aws.String(name))
+	}
+
+	o := iddb.start(ctx, "BatchGetItem", tableSet(names...)...)
+	out, err := iddb.ddb.BatchGetItemWithContext(ctx, in, opts...)
+	if err == nil {
+		for _, items := range out.Responses {
+			o.Items += len(items)
+		}
+		o.capacity(out.ConsumedCapacity...)
+	}
+
+	iddb.observe(ctx, o, err)
+	return out, err
+}
+
+func (iddb *instrumentedDynamo) BatchWriteItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.BatchWriteItemInput,
+	opts ...request.Option,
+) (*dynamodb.BatchWriteItemOutput, error) {
+	var names []*string
+	for name := range in.RequestItems {
+		names = append(names, aws.String(name))
+	}
+
+	o := iddb.start(ctx, "BatchWriteItem", tableSet(names...)...)
+	out, err := iddb.ddb.BatchWriteItemWithContext(ctx, in, opts...)
+	if err == nil {
+		for name, wrs := range in.RequestItems {
+			o.Items += len(wrs) - len(out.UnprocessedItems[name])
+		}
+		o.capacity(out.ConsumedCapacity...)
+	}
+
+	iddb.observe(ctx, o, err)
+	return out, err
+}
+
+// InstrumentedDynamo returns a dynamo interface that records the table, operation, duration,
+// outcome, item count and consumed capacity of every interaction with dynamodb to the sink.
+func InstrumentedDynamo(ddb Dynamo, sink Sink) Dynamo {
+	return &instrumentedDynamo{ddb, sink}
+}
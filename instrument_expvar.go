@@ -0,0 +1,106 @@
+package ddb
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the latency histogram buckets
+var latencyBuckets = []time.Duration{
+	time.Millisecond,
+	2 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// histogram is an expvar.Var that counts durations in (cumulative) latency buckets
+type histogram struct {
+	counts []int64 // one per bucket, plus one for durations over the last bucket
+	total  int64
+	sum    int64
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make([]int64, len(latencyBuckets)+1)}
+}
+
+// observe adds a duration to the histogram
+func (h *histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(latencyBuckets) && d > latencyBuckets[i] {
+		i++
+	}
+
+	atomic.AddInt64(&h.counts[i], 1)
+	atomic.AddInt64(&h.total, 1)
+	atomic.AddInt64(&h.sum, int64(d))
+}
+
+// String encodes the histogram as JSON, with the nr of observations at or below each bucket
+func (h *histogram) String() string {
+	var b strings.Builder
+	var cum int64
+	b.WriteString("{")
+	for i, le := range latencyBuckets {
+		cum += atomic.LoadInt64(&h.counts[i])
+		fmt.Fprintf(&b, "%q: %d, ", "le_"+le.String(), cum)
+	}
+
+	fmt.Fprintf(&b, "%q: %d, %q: %g}", "count", atomic.LoadInt64(&h.total),
+		"sum_ms", float64(atomic.LoadInt64(&h.sum))/float64(time.Millisecond))
+	return b.String()
+}
+
+// expvarSink maintains counters and histograms in an expvar map
+type expvarSink struct {
+	m  *expvar.Map
+	mu sync.Mutex
+}
+
+// histogram returns the histogram for the key, it is created if it doesn't exist yet
+func (s *expvarSink) histogram(key string) *histogram {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if h, ok := s.m.Get(key).(*histogram); ok {
+		return h
+	}
+
+	h := newHistogram()
+	s.m.Set(key, h)
+	return h
+}
+
+func (s *expvarSink) Observe(ctx context.Context, o Observation) {
+	prefix := o.Table + "." + o.Op
+	s.m.Add(prefix+".calls", 1)
+	s.m.Add(prefix+".items", int64(o.Items))
+	if o.Err != nil {
+		s.m.Add(prefix+".errors."+o.ErrClass, 1)
+	}
+
+	if o.ConsumedCapacity != nil {
+		s.m.AddFloat(prefix+".capacity_units", o.CapacityUnits)
+	}
+
+	s.histogram(prefix + ".latency").observe(o.Duration)
+}
+
+// ExpvarSink returns a sink that maintains counters and latency histograms in the expvar map.
+// Variables are keyed by the table and operation, for example: "users.Query.calls".
+func ExpvarSink(m *expvar.Map) Sink {
+	return &expvarSink{m: m}
+}
@@ -0,0 +1,46 @@
+package ddb
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogSink returns a sink that logs every observation to the logger. Successful operations are
+// logged at the provided level, failed operations at least at the warn level.
+func SlogSink(logs *slog.Logger, level slog.Level) Sink {
+	return SinkFunc(func(ctx context.Context, o Observation) {
+		lvl := level
+		if o.Err != nil && lvl < slog.LevelWarn {
+			lvl = slog.LevelWarn
+		}
+
+		if !logs.Enabled(ctx, lvl) {
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("op", o.Op),
+			slog.String("table", o.Table),
+			slog.Duration("duration", o.Duration),
+			slog.Int("items", o.Items),
+		}
+
+		if o.Index != "" {
+			attrs = append(attrs, slog.String("index", o.Index))
+		}
+
+		if o.Page > 0 {
+			attrs = append(attrs, slog.Int("page", o.Page), slog.Bool("more_pages", o.MorePages))
+		}
+
+		if o.ConsumedCapacity != nil {
+			attrs = append(attrs, slog.Float64("capacity_units", o.CapacityUnits))
+		}
+
+		if o.Err != nil {
+			attrs = append(attrs, slog.String("error_class", o.ErrClass), slog.String("error", o.Err.Error()))
+		}
+
+		logs.LogAttrs(ctx, lvl, "ddb: "+o.Op, attrs...)
+	})
+}
@@ -0,0 +1,28 @@
+package ddb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlogSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := SlogSink(slog.New(slog.NewTextHandler(&buf, nil)), slog.LevelDebug)
+
+	sink.Observe(context.Background(), Observation{Op: "Query", Table: "tbl", Duration: time.Second, Items: 2})
+	if buf.Len() != 0 {
+		t.Fatalf("got: %v", buf.String())
+	}
+
+	err := errors.New("foo")
+	sink.Observe(context.Background(), Observation{Op: "Query", Table: "tbl", Err: err, ErrClass: ErrorClass(err)})
+	if act := buf.String(); !strings.Contains(act, "level=WARN") ||
+		!strings.Contains(act, "op=Query table=tbl") || !strings.Contains(act, "error_class=Unknown error=foo") {
+		t.Fatalf("got: %v", act)
+	}
+}
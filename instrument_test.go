@@ -0,0 +1,123 @@
+package ddb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/gohandle/ddb/internal/ddbtest"
+	"github.com/gohandle/ddb/memddb"
+)
+
+func TestErrorClass(t *testing.T) {
+	for i, c := range []struct {
+		err error
+		exp string
+	}{
+		{nil, ""},
+		{context.Canceled, "Canceled"},
+		{fmt.Errorf("foo: %w", context.DeadlineExceeded), "DeadlineExceeded"},
+		{&dynamodb.TransactionCanceledException{}, "TransactionCanceledException"},
+		{fmt.Errorf("foo: %w", awserr.New("ValidationException", "bar", nil)), "ValidationException"},
+		{errors.New("foo"), "Unknown"},
+	} {
+		if act := ErrorClass(c.err); act != c.exp {
+			t.Fatalf("%d: got: %v", i, act)
+		}
+	}
+}
+
+func TestInstrumentedDynamo(t *testing.T) {
+	ctx, tbl := context.Background(), table2(t.Name())
+	mddb := ddbtest.MemDB(t, tbl.createInput())
+
+	var mu sync.Mutex
+	var obs []Observation
+	vars := new(expvar.Map).Init()
+	ddb := InstrumentedDynamo(mddb, MultiSink(ExpvarSink(vars), SinkFunc(func(ctx context.Context, o Observation) {
+		mu.Lock()
+		defer mu.Unlock()
+		obs = append(obs, o)
+	})))
+
+	w := NewWriter()
+	for i := 0; i < 5; i++ {
+		w.Put(tbl.Put1(&table2Entity{ID: i, Kind: 1}))
+	}
+
+	if _, err := w.Run(ctx, ddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	b, q := tbl.ByKind(1)
+	q.SetLimit(2)
+	r, err := Query(b, q).Run(ctx, ddb)
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	for r.Next() {
+	}
+
+	if _, err = Get(tbl.Get1(1)).Run(ctx, InstrumentedDynamo(memddb.New(), ExpvarSink(vars))); err == nil {
+		t.Fatalf("should fail")
+	}
+
+	if len(obs) != 4 {
+		t.Fatalf("got: %v", obs)
+	}
+
+	if o := obs[0]; o.Op != "TransactWriteItems" || o.Table != string(tbl) || o.Items != 5 || o.Err != nil {
+		t.Fatalf("got: %+v", o)
+	}
+
+	for i, o := range obs[1:] {
+		if o.Op != "Query" || o.Index != "gsi1" || o.Page != i+1 || o.MorePages != (i < 2) || o.Duration <= 0 {
+			t.Fatalf("got: %+v", o)
+		}
+	}
+
+	if act := vars.Get(string(tbl) + ".Query.calls").String(); act != "3" {
+		t.Fatalf("got: %v", act)
+	}
+
+	if act := vars.Get(string(tbl) + ".Query.items").String(); act != "5" {
+		t.Fatalf("got: %v", act)
+	}
+
+	if act := vars.Get(string(tbl) + ".GetItem.errors.ResourceNotFoundException").String(); act != "1" {
+		t.Fatalf("got: %v", act)
+	}
+
+	var hist map[string]float64
+	if err = json.Unmarshal([]byte(vars.Get(string(tbl)+".Query.latency").String()), &hist); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if hist["count"] != 3 || hist["le_5s"] != 3 {
+		t.Fatalf("got: %v", hist)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram()
+	h.observe(time.Millisecond)
+	h.observe(3 * time.Millisecond)
+	h.observe(time.Minute)
+
+	var act map[string]float64
+	if err := json.Unmarshal([]byte(h.String()), &act); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if act["le_1ms"] != 1 || act["le_2ms"] != 1 || act["le_5ms"] != 2 || act["le_5s"] != 2 || act["count"] != 3 {
+		t.Fatalf("got: %v", act)
+	}
+}
@@ -0,0 +1,51 @@
+// Package ddbtest provides the DynamoDB fixtures that are shared by the tests of all packages
+package ddbtest
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/gohandle/ddb/ddblocal"
+	"github.com/gohandle/ddb/memddb"
+)
+
+// LocalDB runs an in-memory DynamoDB, served over the DynamoDB HTTP protocol, for the duration of
+// the test while creating any tables that are provided. It returns a client of the sdk.
+func LocalDB(tb testing.TB, tbls ...*dynamodb.CreateTableInput) *dynamodb.DynamoDB {
+	srv := httptest.NewServer(ddblocal.New(nil))
+	tb.Cleanup(srv.Close)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("eu-west-1"),
+		Endpoint:    aws.String(srv.URL),
+		Credentials: credentials.NewStaticCredentials("local", "local", ""),
+	})
+	if err != nil {
+		tb.Fatalf("failed to create local session: %v", err)
+	}
+
+	ddb := dynamodb.New(sess)
+	for _, in := range tbls {
+		if _, err = ddb.CreateTable(in); err != nil {
+			tb.Fatalf("failed to create table %v: %v", in, err)
+		}
+	}
+
+	return ddb
+}
+
+// MemDB returns an in-memory DynamoDB, without the HTTP roundtrip, with the provided tables
+func MemDB(tb testing.TB, tbls ...*dynamodb.CreateTableInput) *memddb.DB {
+	db := memddb.New()
+	for _, in := range tbls {
+		if _, err := db.CreateTable(in); err != nil {
+			tb.Fatalf("failed to create table %v: %v", in, err)
+		}
+	}
+
+	return db
+}
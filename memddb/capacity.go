@@ -0,0 +1,185 @@
+package memddb
+
+import (
+	"math"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// capacity accumulates the capacity units that an operation consumed on one table and its
+// indexes. The units are computed from item sizes the way DynamoDB documents them but, since
+// there is no storage layer, they are an approximation for items near the size boundaries.
+type capacity struct {
+	tbl     *table
+	read    bool
+	table   float64
+	indexes map[string]float64
+}
+
+// newCapacity inits the capacity for reads or writes on a table
+func newCapacity(tbl *table, read bool) *capacity {
+	return &capacity{tbl: tbl, read: read, indexes: map[string]float64{}}
+}
+
+// capacities accumulates consumed capacity for operations that involve several tables
+type capacities struct {
+	order   []*capacity
+	byTable map[*table]*capacity
+}
+
+// of returns the capacity of a table, it is created on first use
+func (cs *capacities) of(tbl *table, read bool) *capacity {
+	if cs.byTable == nil {
+		cs.byTable = map[*table]*capacity{}
+	}
+
+	c, ok := cs.byTable[tbl]
+	if !ok {
+		c = newCapacity(tbl, read)
+		cs.byTable[tbl] = c
+		cs.order = append(cs.order, c)
+	}
+
+	return c
+}
+
+// consumed returns the consumed capacity of all tables as asked for by 'mode'
+func (cs *capacities) consumed(mode *string) (ccs []*dynamodb.ConsumedCapacity) {
+	for _, c := range cs.order {
+		if cc := c.consumed(mode); cc != nil {
+			ccs = append(ccs, cc)
+		}
+	}
+	return
+}
+
+// readUnits returns the read capacity units for reading 'size' bytes
+func readUnits(size int, consistent bool) float64 {
+	u := math.Max(1, math.Ceil(float64(size)/4096))
+	if !consistent {
+		u /= 2
+	}
+	return u
+}
+
+// writeUnits returns the write capacity units for writing an item of 'size' bytes
+func writeUnits(size int) float64 {
+	return math.Max(1, math.Ceil(float64(size)/1024))
+}
+
+// inIndex returns whether an item has the key attributes of an index
+func inIndex(idx *index, item map[string]*dynamodb.AttributeValue) bool {
+	if item == nil {
+		return false
+	}
+
+	for _, n := range idx.keys.names() {
+		if _, ok := item[n]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// addWrite charges a write to the table and to every index entry that it creates, updates or
+// deletes. Transactional writes are charged twice.
+func (c *capacity) addWrite(w *write, factor float64) {
+	c.table += writeUnits(int(math.Max(float64(itemSize(w.old)), float64(itemSize(w.new))))) * factor
+	if w.check {
+		return
+	}
+
+	newItem := w.new
+	if w.del {
+		newItem = nil
+	}
+
+	for _, idx := range c.tbl.indexes {
+		oldIn, newIn := inIndex(idx, w.old), inIndex(idx, newItem)
+		if !oldIn && !newIn {
+			continue
+		}
+
+		v := &view{keys: idx.keys, index: idx, tbl: c.tbl}
+		var size int
+		if oldIn {
+			size = itemSize(v.projectIndex(w.old))
+		}
+
+		if newIn {
+			size = int(math.Max(float64(size), float64(itemSize(v.projectIndex(newItem)))))
+		}
+
+		u := writeUnits(size)
+		if oldIn && newIn && v.compare(w.old, newItem) != 0 {
+			u *= 2 // the index entry moves: a delete and a put
+		}
+
+		c.indexes[idx.name] += u * factor
+	}
+}
+
+// addRead charges a read of 'size' bytes from the table or one of its indexes
+func (c *capacity) addRead(idx *index, size int, consistent bool, factor float64) {
+	if idx != nil {
+		c.indexes[idx.name] += readUnits(size, consistent) * factor
+		return
+	}
+
+	c.table += readUnits(size, consistent) * factor
+}
+
+// consumed returns the consumed capacity as asked for by 'mode'
+func (c *capacity) consumed(mode *string) *dynamodb.ConsumedCapacity {
+	switch aws.StringValue(mode) {
+	case dynamodb.ReturnConsumedCapacityTotal, dynamodb.ReturnConsumedCapacityIndexes:
+	default:
+		return nil
+	}
+
+	total := c.table
+	for _, u := range c.indexes {
+		total += u
+	}
+
+	cc := &dynamodb.ConsumedCapacity{
+		TableName:     c.tbl.desc.TableName,
+		CapacityUnits: aws.Float64(total),
+	}
+
+	if c.read {
+		cc.ReadCapacityUnits = aws.Float64(total)
+	} else {
+		cc.WriteCapacityUnits = aws.Float64(total)
+	}
+
+	if aws.StringValue(mode) != dynamodb.ReturnConsumedCapacityIndexes {
+		return cc
+	}
+
+	cc.Table = c.units(c.table)
+	for name, u := range c.indexes {
+		if c.tbl.indexes[name].global {
+			if cc.GlobalSecondaryIndexes == nil {
+				cc.GlobalSecondaryIndexes = map[string]*dynamodb.Capacity{}
+			}
+			cc.GlobalSecondaryIndexes[name] = c.units(u)
+		} else {
+			if cc.LocalSecondaryIndexes == nil {
+				cc.LocalSecondaryIndexes = map[string]*dynamodb.Capacity{}
+			}
+			cc.LocalSecondaryIndexes[name] = c.units(u)
+		}
+	}
+
+	return cc
+}
+
+// units returns the capacity of the table or an index
+func (c *capacity) units(u float64) *dynamodb.Capacity {
+	if c.read {
+		return &dynamodb.Capacity{CapacityUnits: aws.Float64(u), ReadCapacityUnits: aws.Float64(u)}
+	}
+	return &dynamodb.Capacity{CapacityUnits: aws.Float64(u), WriteCapacityUnits: aws.Float64(u)}
+}
@@ -0,0 +1,353 @@
+package memddb
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// resolve returns the value at path 'p' in the item, or nil if it doesn't exist
+func resolve(item map[string]*dynamodb.AttributeValue, p path) (av *dynamodb.AttributeValue) {
+	if len(p) < 1 || p[0].isIdx {
+		return nil
+	}
+
+	av = item[p[0].name]
+	for _, e := range p[1:] {
+		switch {
+		case av == nil:
+			return nil
+		case e.isIdx && av.L != nil:
+			if e.index >= len(av.L) {
+				return nil
+			}
+			av = av.L[e.index]
+		case !e.isIdx && av.M != nil:
+			av = av.M[e.name]
+		default:
+			return nil
+		}
+	}
+
+	return av
+}
+
+// evalOperand returns the value of an operand for the item, nil if it doesn't exist
+func evalOperand(item map[string]*dynamodb.AttributeValue, o operand) (*dynamodb.AttributeValue, error) {
+	switch o := o.(type) {
+	case *valueOperand:
+		return o.av, nil
+	case *pathOperand:
+		return resolve(item, o.p), nil
+	case *sizeOperand:
+		av := resolve(item, o.p)
+		var n int
+		switch typeOf(av) {
+		case typeS:
+			n = len(*av.S)
+		case typeB:
+			n = len(av.B)
+		case typeSS, typeNS, typeBS:
+			n = len(setKeys(av))
+		case typeL:
+			n = len(av.L)
+		case typeM:
+			n = len(av.M)
+		default:
+			return nil, nil
+		}
+
+		return &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(n))}, nil
+	case *ifNotExists:
+		if av := resolve(item, o.p); av != nil {
+			return av, nil
+		}
+
+		return evalOperand(item, o.v)
+	case *listAppend:
+		a, err := evalOperand(item, o.a)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := evalOperand(item, o.b)
+		if err != nil {
+			return nil, err
+		}
+
+		if typeOf(a) != typeL || typeOf(b) != typeL {
+			return nil, validationErrorf("Invalid UpdateExpression: Incorrect operand type for operator or function; operator or function: list_append")
+		}
+
+		l := &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}}
+		l.L = append(l.L, a.L...)
+		l.L = append(l.L, b.L...)
+		return copyAV(l), nil
+	case *arithmetic:
+		a, err := evalOperand(item, o.a)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := evalOperand(item, o.b)
+		if err != nil {
+			return nil, err
+		}
+
+		if a == nil || b == nil {
+			return nil, validationErrorf("The provided expression refers to an attribute that does not exist in the item")
+		}
+
+		if typeOf(a) != typeN || typeOf(b) != typeN {
+			return nil, validationErrorf("An operand in the update expression has an incorrect data type")
+		}
+
+		ra, err := parseNum(*a.N)
+		if err != nil {
+			return nil, err
+		}
+
+		rb, err := parseNum(*b.N)
+		if err != nil {
+			return nil, err
+		}
+
+		if o.op == "+" {
+			ra.Add(ra, rb)
+		} else {
+			ra.Sub(ra, rb)
+		}
+
+		return &dynamodb.AttributeValue{N: aws.String(formatNum(ra))}, nil
+	}
+
+	return nil, validationErrorf("Invalid expression: unsupported operand")
+}
+
+// evalCond evaluates a condition against the item, a nil condition is always true
+func evalCond(item map[string]*dynamodb.AttributeValue, c cond) (bool, error) {
+	switch c := c.(type) {
+	case nil:
+		return true, nil
+	case *andCond:
+		l, err := evalCond(item, c.l)
+		if err != nil || !l {
+			return false, err
+		}
+		return evalCond(item, c.r)
+	case *orCond:
+		l, err := evalCond(item, c.l)
+		if err != nil || l {
+			return l, err
+		}
+		return evalCond(item, c.r)
+	case *notCond:
+		v, err := evalCond(item, c.c)
+		return !v, err
+	case *compareCond:
+		l, err := evalOperand(item, c.l)
+		if err != nil {
+			return false, err
+		}
+
+		r, err := evalOperand(item, c.r)
+		if err != nil {
+			return false, err
+		}
+
+		switch c.op {
+		case "=":
+			return equalAV(l, r), nil
+		case "<>":
+			return !equalAV(l, r), nil
+		}
+
+		cmp, ok := compareAV(l, r)
+		if !ok {
+			return false, nil
+		}
+
+		switch c.op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+	case *betweenCond:
+		v, err := evalOperand(item, c.v)
+		if err != nil {
+			return false, err
+		}
+
+		lo, err := evalOperand(item, c.lo)
+		if err != nil {
+			return false, err
+		}
+
+		hi, err := evalOperand(item, c.hi)
+		if err != nil {
+			return false, err
+		}
+
+		if cmp, ok := compareAV(lo, hi); ok && cmp > 0 {
+			return false, validationErrorf("Invalid ConditionExpression: The BETWEEN operator requires upper bound to be greater than or equal to lower bound")
+		}
+
+		clo, ok1 := compareAV(v, lo)
+		chi, ok2 := compareAV(v, hi)
+		return ok1 && ok2 && clo >= 0 && chi <= 0, nil
+	case *inCond:
+		v, err := evalOperand(item, c.v)
+		if err != nil {
+			return false, err
+		}
+
+		for _, o := range c.list {
+			e, err := evalOperand(item, o)
+			if err != nil {
+				return false, err
+			}
+
+			if equalAV(v, e) {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	case *funcCond:
+		return evalFunc(item, c)
+	}
+
+	return false, validationErrorf("Invalid expression: unsupported condition")
+}
+
+// evalFunc evaluates a function condition
+func evalFunc(item map[string]*dynamodb.AttributeValue, c *funcCond) (bool, error) {
+	var args []*dynamodb.AttributeValue
+	for _, o := range c.args {
+		av, err := evalOperand(item, o)
+		if err != nil {
+			return false, err
+		}
+
+		args = append(args, av)
+	}
+
+	switch c.name {
+	case "attribute_exists":
+		return args[0] != nil, nil
+	case "attribute_not_exists":
+		return args[0] == nil, nil
+	case "attribute_type":
+		if typeOf(args[1]) != typeS {
+			return false, validationErrorf("Invalid ConditionExpression: Incorrect operand type for operator or function; operator or function: attribute_type")
+		}
+
+		return args[0] != nil && typeOf(args[0]) == *args[1].S, nil
+	case "begins_with":
+		switch {
+		case typeOf(args[0]) == typeS && typeOf(args[1]) == typeS:
+			return strings.HasPrefix(*args[0].S, *args[1].S), nil
+		case typeOf(args[0]) == typeB && typeOf(args[1]) == typeB:
+			return bytes.HasPrefix(args[0].B, args[1].B), nil
+		}
+		return false, nil
+	case "contains":
+		a, b := args[0], args[1]
+		switch typeOf(a) {
+		case typeS:
+			return typeOf(b) == typeS && strings.Contains(*a.S, *b.S), nil
+		case typeB:
+			return typeOf(b) == typeB && bytes.Contains(a.B, b.B), nil
+		case typeSS, typeNS, typeBS:
+			if typeOf(b) != typeOf(a)[:1] {
+				return false, nil
+			}
+
+			_, ok := setKeys(a)[string(keyBytes(b))]
+			return ok, nil
+		case typeL:
+			for _, e := range a.L {
+				if equalAV(e, b) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	}
+
+	return false, validationErrorf("Invalid expression: unsupported function %s", c.name)
+}
+
+// project returns a copy of the item with only the attributes in the paths
+func project(item map[string]*dynamodb.AttributeValue, paths []path) map[string]*dynamodb.AttributeValue {
+	if paths == nil {
+		return copyItem(item)
+	}
+
+	out := map[string]*dynamodb.AttributeValue{}
+	for _, p := range paths {
+		av := resolve(item, p)
+		if av == nil {
+			continue
+		}
+
+		// rebuild the nested structure, list indexes are compacted in the order of projection
+		cur := out
+		for i, e := range p {
+			last := i == len(p)-1
+			if e.isIdx {
+				break
+			}
+
+			if last || p[i+1].isIdx {
+				if last {
+					cur[e.name] = copyAV(av)
+				} else {
+					projectList(cur, e.name, item, p[:i+1], p[i+1:], av)
+				}
+				break
+			}
+
+			nxt, ok := cur[e.name]
+			if !ok || nxt.M == nil {
+				nxt = &dynamodb.AttributeValue{M: map[string]*dynamodb.AttributeValue{}}
+				cur[e.name] = nxt
+			}
+			cur = nxt.M
+		}
+	}
+
+	return out
+}
+
+// projectList handles projection of a path that continues with a list index, the projected
+// value is stored in a list that only holds the selected elements.
+func projectList(
+	cur map[string]*dynamodb.AttributeValue,
+	name string,
+	item map[string]*dynamodb.AttributeValue,
+	head, tail path,
+	av *dynamodb.AttributeValue,
+) {
+	l, ok := cur[name]
+	if !ok || l.L == nil {
+		l = &dynamodb.AttributeValue{L: []*dynamodb.AttributeValue{}}
+		cur[name] = l
+	}
+
+	if len(tail) == 1 {
+		l.L = append(l.L, copyAV(av))
+		return
+	}
+
+	// deeper nesting below a list element, copy the whole element
+	l.L = append(l.L, copyAV(resolve(item, append(append(path{}, head...), tail[0]))))
+}
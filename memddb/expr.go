@@ -0,0 +1,756 @@
+package memddb
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// token kinds produced by the lexer
+const (
+	tokEOF = iota
+	tokIdent
+	tokName
+	tokValue
+	tokNumber
+	tokPunct
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+// lex splits an expression into tokens
+func lex(s string) (toks []token, err error) {
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		c := rs[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '#' || c == ':' || isIdentRune(c) && !unicode.IsDigit(c):
+			j := i + 1
+			for j < len(rs) && isIdentRune(rs[j]) {
+				j++
+			}
+
+			kind := tokIdent
+			if c == '#' {
+				kind = tokName
+			} else if c == ':' {
+				kind = tokValue
+			}
+
+			if j == i+1 && kind != tokIdent {
+				return nil, validationErrorf("Invalid expression: Syntax error; token: %q", string(c))
+			}
+
+			toks = append(toks, token{kind, string(rs[i:j])})
+			i = j
+		case unicode.IsDigit(c):
+			j := i + 1
+			for j < len(rs) && unicode.IsDigit(rs[j]) {
+				j++
+			}
+
+			toks = append(toks, token{tokNumber, string(rs[i:j])})
+			i = j
+		case c == '<' || c == '>':
+			if i+1 < len(rs) && (rs[i+1] == '=' || (c == '<' && rs[i+1] == '>')) {
+				toks = append(toks, token{tokPunct, string(rs[i : i+2])})
+				i += 2
+				continue
+			}
+
+			toks = append(toks, token{tokPunct, string(c)})
+			i++
+		case strings.ContainsRune("()[],.=+-", c):
+			toks = append(toks, token{tokPunct, string(c)})
+			i++
+		default:
+			return nil, validationErrorf("Invalid expression: Syntax error; token: %q", string(c))
+		}
+	}
+
+	return append(toks, token{kind: tokEOF}), nil
+}
+
+func isIdentRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// pathElem is one element of a document path: either a name or a list index
+type pathElem struct {
+	name  string
+	index int
+	isIdx bool
+}
+
+// path points to an (nested) attribute in an item
+type path []pathElem
+
+func (p path) String() string {
+	var b strings.Builder
+	for i, e := range p {
+		if e.isIdx {
+			b.WriteString("[" + strconv.Itoa(e.index) + "]")
+			continue
+		}
+
+		if i > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(e.name)
+	}
+	return b.String()
+}
+
+// operand kinds that can appear in conditions and update actions
+type (
+	pathOperand  struct{ p path }
+	valueOperand struct{ av *dynamodb.AttributeValue }
+	sizeOperand  struct{ p path }
+	ifNotExists  struct {
+		p path
+		v operand
+	}
+	listAppend struct{ a, b operand }
+	arithmetic struct {
+		op   string
+		a, b operand
+	}
+)
+
+type operand interface{}
+
+// condition kinds
+type (
+	compareCond struct {
+		op   string
+		l, r operand
+	}
+	betweenCond struct{ v, lo, hi operand }
+	inCond      struct {
+		v    operand
+		list []operand
+	}
+	andCond  struct{ l, r cond }
+	orCond   struct{ l, r cond }
+	notCond  struct{ c cond }
+	funcCond struct {
+		name string
+		args []operand
+	}
+)
+
+type cond interface{}
+
+// update actions
+type (
+	setAction struct {
+		p path
+		v operand
+	}
+	removeAction struct{ p path }
+	addAction    struct {
+		p path
+		v operand
+	}
+	deleteAction struct {
+		p path
+		v operand
+	}
+)
+
+// update holds the parsed actions of an update expression
+type update struct {
+	set    []setAction
+	remove []removeAction
+	add    []addAction
+	delete []deleteAction
+}
+
+// parser parses expressions while resolving the placeholders
+type parser struct {
+	toks   []token
+	pos    int
+	names  map[string]*string
+	values map[string]*dynamodb.AttributeValue
+	used   map[string]bool
+}
+
+func newParser(
+	s string,
+	names map[string]*string,
+	values map[string]*dynamodb.AttributeValue,
+	used map[string]bool,
+) (*parser, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if used == nil {
+		used = map[string]bool{}
+	}
+
+	return &parser{toks: toks, names: names, values: values, used: used}, nil
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && strings.EqualFold(t.text, kw)
+}
+
+func (p *parser) isPunct(s string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == s
+}
+
+func (p *parser) expectPunct(s string) error {
+	if !p.isPunct(s) {
+		return p.syntaxError()
+	}
+
+	p.next()
+	return nil
+}
+
+func (p *parser) syntaxError() error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return validationErrorf("Invalid expression: Syntax error; token: <EOF>")
+	}
+	return validationErrorf("Invalid expression: Syntax error; token: %q", t.text)
+}
+
+func (p *parser) expectEOF() error {
+	if p.peek().kind != tokEOF {
+		return p.syntaxError()
+	}
+	return nil
+}
+
+// parsePath parses a document path
+func (p *parser) parsePath() (pth path, err error) {
+	for {
+		t := p.next()
+		switch t.kind {
+		case tokName:
+			name, ok := p.names[t.text]
+			if !ok || name == nil {
+				return nil, validationErrorf(
+					"Invalid expression: An expression attribute name used in the document path is not defined; attribute name: %s", t.text)
+			}
+
+			p.used[t.text] = true
+			pth = append(pth, pathElem{name: aws.StringValue(name)})
+		case tokIdent:
+			pth = append(pth, pathElem{name: t.text})
+		default:
+			p.pos--
+			return nil, p.syntaxError()
+		}
+
+		for p.isPunct("[") {
+			p.next()
+			n := p.next()
+			if n.kind != tokNumber {
+				p.pos--
+				return nil, p.syntaxError()
+			}
+
+			idx, _ := strconv.Atoi(n.text)
+			pth = append(pth, pathElem{index: idx, isIdx: true})
+			if err = p.expectPunct("]"); err != nil {
+				return nil, err
+			}
+		}
+
+		if !p.isPunct(".") {
+			return pth, nil
+		}
+
+		p.next()
+	}
+}
+
+// parsePaths parses a comma separated list of paths
+func (p *parser) parsePaths() (paths []path, err error) {
+	for {
+		var pth path
+		if pth, err = p.parsePath(); err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, pth)
+		if !p.isPunct(",") {
+			return paths, nil
+		}
+
+		p.next()
+	}
+}
+
+// parseValue parses a value placeholder
+func (p *parser) parseValue() (operand, error) {
+	t := p.next()
+	if t.kind != tokValue {
+		p.pos--
+		return nil, p.syntaxError()
+	}
+
+	av, ok := p.values[t.text]
+	if !ok || av == nil {
+		return nil, validationErrorf(
+			"Invalid expression: An expression attribute value used in expression is not defined; attribute value: %s", t.text)
+	}
+
+	if err := validateAV(av); err != nil {
+		return nil, err
+	}
+
+	p.used[t.text] = true
+	return &valueOperand{av}, nil
+}
+
+// parseOperand parses an operand as it may appear in a condition
+func (p *parser) parseOperand() (operand, error) {
+	t := p.peek()
+	switch {
+	case t.kind == tokValue:
+		return p.parseValue()
+	case t.kind == tokIdent && strings.EqualFold(t.text, "size") &&
+		p.toks[p.pos+1].kind == tokPunct && p.toks[p.pos+1].text == "(":
+		p.next()
+		p.next()
+		pth, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+
+		return &sizeOperand{pth}, p.expectPunct(")")
+	default:
+		pth, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+
+		return &pathOperand{pth}, nil
+	}
+}
+
+// parseCondition parses a full condition expression
+func (p *parser) parseCondition() (c cond, err error) {
+	if c, err = p.parseOr(); err != nil {
+		return nil, err
+	}
+
+	return c, p.expectEOF()
+}
+
+func (p *parser) parseOr() (c cond, err error) {
+	if c, err = p.parseAnd(); err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("OR") {
+		p.next()
+
+		var r cond
+		if r, err = p.parseAnd(); err != nil {
+			return nil, err
+		}
+
+		c = &orCond{c, r}
+	}
+
+	return c, nil
+}
+
+func (p *parser) parseAnd() (c cond, err error) {
+	if c, err = p.parseNot(); err != nil {
+		return nil, err
+	}
+
+	for p.isKeyword("AND") {
+		p.next()
+
+		var r cond
+		if r, err = p.parseNot(); err != nil {
+			return nil, err
+		}
+
+		c = &andCond{c, r}
+	}
+
+	return c, nil
+}
+
+func (p *parser) parseNot() (c cond, err error) {
+	if p.isKeyword("NOT") {
+		p.next()
+		if c, err = p.parseNot(); err != nil {
+			return nil, err
+		}
+
+		return &notCond{c}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+// condFuncs are the functions that can be used as a condition
+var condFuncs = map[string]int{
+	"attribute_exists":     1,
+	"attribute_not_exists": 1,
+	"attribute_type":       2,
+	"begins_with":          2,
+	"contains":             2,
+}
+
+func (p *parser) parsePrimary() (c cond, err error) {
+	if p.isPunct("(") {
+		p.next()
+		if c, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+
+		return c, p.expectPunct(")")
+	}
+
+	t := p.peek()
+	if nargs, ok := condFuncs[strings.ToLower(t.text)]; ok && t.kind == tokIdent &&
+		p.toks[p.pos+1].kind == tokPunct && p.toks[p.pos+1].text == "(" {
+		p.next()
+		p.next()
+
+		fc := &funcCond{name: strings.ToLower(t.text)}
+		for i := 0; i < nargs; i++ {
+			if i > 0 {
+				if err = p.expectPunct(","); err != nil {
+					return nil, err
+				}
+			}
+
+			var o operand
+			if o, err = p.parseOperand(); err != nil {
+				return nil, err
+			}
+
+			fc.args = append(fc.args, o)
+		}
+
+		if _, ok := fc.args[0].(*pathOperand); !ok && fc.name != "contains" && fc.name != "begins_with" {
+			return nil, validationErrorf("Invalid ConditionExpression: Operator or function requires a document path; operator or function: %s", fc.name)
+		}
+
+		return fc, p.expectPunct(")")
+	}
+
+	var l operand
+	if l, err = p.parseOperand(); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.isKeyword("BETWEEN"):
+		p.next()
+
+		bc := &betweenCond{v: l}
+		if bc.lo, err = p.parseOperand(); err != nil {
+			return nil, err
+		}
+
+		if !p.isKeyword("AND") {
+			return nil, p.syntaxError()
+		}
+
+		p.next()
+		if bc.hi, err = p.parseOperand(); err != nil {
+			return nil, err
+		}
+
+		return bc, nil
+	case p.isKeyword("IN"):
+		p.next()
+		if err = p.expectPunct("("); err != nil {
+			return nil, err
+		}
+
+		ic := &inCond{v: l}
+		for {
+			var o operand
+			if o, err = p.parseOperand(); err != nil {
+				return nil, err
+			}
+
+			ic.list = append(ic.list, o)
+			if !p.isPunct(",") {
+				break
+			}
+
+			p.next()
+		}
+
+		return ic, p.expectPunct(")")
+	}
+
+	op := p.next()
+	switch op.text {
+	case "=", "<>", "<", "<=", ">", ">=":
+	default:
+		p.pos--
+		return nil, p.syntaxError()
+	}
+
+	cc := &compareCond{op: op.text, l: l}
+	if cc.r, err = p.parseOperand(); err != nil {
+		return nil, err
+	}
+
+	return cc, nil
+}
+
+// parseUpdate parses a full update expression
+func (p *parser) parseUpdate() (u *update, err error) {
+	u = &update{}
+	seen := map[string]bool{}
+	for p.peek().kind != tokEOF {
+		t := p.next()
+		clause := strings.ToUpper(t.text)
+		if t.kind != tokIdent || seen[clause] {
+			p.pos--
+			return nil, p.syntaxError()
+		}
+
+		seen[clause] = true
+		for {
+			switch clause {
+			case "SET":
+				var a setAction
+				if a.p, err = p.parsePath(); err != nil {
+					return nil, err
+				}
+
+				if err = p.expectPunct("="); err != nil {
+					return nil, err
+				}
+
+				if a.v, err = p.parseSetValue(); err != nil {
+					return nil, err
+				}
+
+				u.set = append(u.set, a)
+			case "REMOVE":
+				var a removeAction
+				if a.p, err = p.parsePath(); err != nil {
+					return nil, err
+				}
+
+				u.remove = append(u.remove, a)
+			case "ADD":
+				var a addAction
+				if a.p, err = p.parsePath(); err != nil {
+					return nil, err
+				}
+
+				if a.v, err = p.parseValue(); err != nil {
+					return nil, err
+				}
+
+				u.add = append(u.add, a)
+			case "DELETE":
+				var a deleteAction
+				if a.p, err = p.parsePath(); err != nil {
+					return nil, err
+				}
+
+				if a.v, err = p.parseValue(); err != nil {
+					return nil, err
+				}
+
+				u.delete = append(u.delete, a)
+			default:
+				p.pos--
+				return nil, p.syntaxError()
+			}
+
+			if !p.isPunct(",") {
+				break
+			}
+
+			p.next()
+		}
+	}
+
+	if len(seen) == 0 {
+		return nil, validationErrorf("Invalid UpdateExpression: The expression can not be empty;")
+	}
+
+	return u, nil
+}
+
+// parseSetValue parses the right hand side of a SET action
+func (p *parser) parseSetValue() (o operand, err error) {
+	if o, err = p.parseSetOperand(); err != nil {
+		return nil, err
+	}
+
+	if p.isPunct("+") || p.isPunct("-") {
+		op := p.next().text
+
+		var r operand
+		if r, err = p.parseSetOperand(); err != nil {
+			return nil, err
+		}
+
+		return &arithmetic{op, o, r}, nil
+	}
+
+	return o, nil
+}
+
+func (p *parser) parseSetOperand() (o operand, err error) {
+	t := p.peek()
+	isCall := t.kind == tokIdent && p.toks[p.pos+1].kind == tokPunct && p.toks[p.pos+1].text == "("
+	switch {
+	case isCall && strings.EqualFold(t.text, "if_not_exists"):
+		p.next()
+		p.next()
+
+		ine := &ifNotExists{}
+		if ine.p, err = p.parsePath(); err != nil {
+			return nil, err
+		}
+
+		if err = p.expectPunct(","); err != nil {
+			return nil, err
+		}
+
+		if ine.v, err = p.parseSetOperand(); err != nil {
+			return nil, err
+		}
+
+		return ine, p.expectPunct(")")
+	case isCall && strings.EqualFold(t.text, "list_append"):
+		p.next()
+		p.next()
+
+		la := &listAppend{}
+		if la.a, err = p.parseSetOperand(); err != nil {
+			return nil, err
+		}
+
+		if err = p.expectPunct(","); err != nil {
+			return nil, err
+		}
+
+		if la.b, err = p.parseSetOperand(); err != nil {
+			return nil, err
+		}
+
+		return la, p.expectPunct(")")
+	case t.kind == tokValue:
+		return p.parseValue()
+	default:
+		pth, err := p.parsePath()
+		if err != nil {
+			return nil, err
+		}
+
+		return &pathOperand{pth}, nil
+	}
+}
+
+// parseCondExpr parses an optional condition expression
+func parseCondExpr(
+	expr *string,
+	names map[string]*string,
+	values map[string]*dynamodb.AttributeValue,
+	used map[string]bool,
+) (cond, error) {
+	if expr == nil {
+		return nil, nil
+	}
+
+	p, err := newParser(*expr, names, values, used)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parseCondition()
+}
+
+// parseProjection parses an optional projection expression
+func parseProjection(expr *string, names map[string]*string, used map[string]bool) ([]path, error) {
+	if expr == nil {
+		return nil, nil
+	}
+
+	p, err := newParser(*expr, names, nil, used)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := p.parsePaths()
+	if err != nil {
+		return nil, err
+	}
+
+	if a, b, ok := overlapping(paths); ok {
+		return nil, validationErrorf("Invalid ProjectionExpression: Two document paths overlap with each other; must remove or rewrite one of these paths; path one: [%s], path two: [%s]", a, b)
+	}
+
+	return paths, p.expectEOF()
+}
+
+// parseUpdateExpr parses an update expression
+func parseUpdateExpr(
+	expr *string,
+	names map[string]*string,
+	values map[string]*dynamodb.AttributeValue,
+	used map[string]bool,
+) (*update, error) {
+	if expr == nil {
+		return nil, nil
+	}
+
+	p, err := newParser(*expr, names, values, used)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parseUpdate()
+}
+
+// checkUnused returns an error if a placeholder was provided that is not used by any expression
+func checkUnused(names map[string]*string, values map[string]*dynamodb.AttributeValue, used map[string]bool) error {
+	for k := range names {
+		if !used[k] {
+			return validationErrorf("Value provided in ExpressionAttributeNames unused in expressions: keys: {%s}", k)
+		}
+	}
+
+	for k := range values {
+		if !used[k] {
+			return validationErrorf("Value provided in ExpressionAttributeValues unused in expressions: keys: {%s}", k)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,398 @@
+package memddb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// write is a validated write operation on a single item that can be committed once its
+// condition is checked.
+type write struct {
+	tbl     *table
+	id      string
+	key     map[string]*dynamodb.AttributeValue
+	old     map[string]*dynamodb.AttributeValue
+	new     map[string]*dynamodb.AttributeValue
+	cond    cond
+	del     bool
+	check   bool
+	updated []string
+}
+
+// ok evaluates the condition of the write against the current item
+func (w *write) ok() (bool, error) {
+	return evalCond(w.old, w.cond)
+}
+
+// commit stores the result of the write
+func (w *write) commit() {
+	switch {
+	case w.check:
+	case w.del:
+		delete(w.tbl.items, w.id)
+	default:
+		w.tbl.items[w.id] = w.new
+	}
+}
+
+// returnValues returns the attributes that are asked for by 'rv'
+func (w *write) returnValues(rv *string) map[string]*dynamodb.AttributeValue {
+	var src map[string]*dynamodb.AttributeValue
+	switch aws.StringValue(rv) {
+	case dynamodb.ReturnValueAllOld:
+		return copyItem(w.old)
+	case dynamodb.ReturnValueAllNew:
+		return copyItem(w.new)
+	case dynamodb.ReturnValueUpdatedOld:
+		src = w.old
+	case dynamodb.ReturnValueUpdatedNew:
+		src = w.new
+	default:
+		return nil
+	}
+
+	out := map[string]*dynamodb.AttributeValue{}
+	for _, name := range w.updated {
+		if av, ok := src[name]; ok {
+			out[name] = copyAV(av)
+		}
+	}
+
+	if len(out) == 0 {
+		return nil
+	}
+
+	return out
+}
+
+// conditionFailed returns the error that DynamoDB returns when a condition fails
+func conditionFailed() error {
+	return &dynamodb.ConditionalCheckFailedException{
+		Message_: aws.String("The conditional request failed"),
+	}
+}
+
+// checkReturnValues validates the ReturnValues parameter
+func checkReturnValues(rv *string, allowed ...string) error {
+	if rv == nil {
+		return nil
+	}
+
+	for _, a := range append(allowed, dynamodb.ReturnValueNone) {
+		if *rv == a {
+			return nil
+		}
+	}
+
+	return validationErrorf("Return values set to invalid value")
+}
+
+// prepPut validates a put operation
+func (db *DB) prepPut(
+	tableName *string,
+	item map[string]*dynamodb.AttributeValue,
+	condExpr *string,
+	names map[string]*string,
+	values map[string]*dynamodb.AttributeValue,
+) (w *write, err error) {
+	w = &write{}
+	if w.tbl, err = db.table(tableName); err != nil {
+		return nil, err
+	}
+
+	used := map[string]bool{}
+	if w.cond, err = parseCondExpr(condExpr, names, values, used); err != nil {
+		return nil, err
+	}
+
+	if err = checkUnused(names, values, used); err != nil {
+		return nil, err
+	}
+
+	if w.id, err = w.tbl.keyID(item); err != nil {
+		return nil, err
+	}
+
+	if err = w.tbl.validateItem(item); err != nil {
+		return nil, err
+	}
+
+	w.key = w.tbl.keyOf(item)
+	w.old = w.tbl.items[w.id]
+	w.new = copyItem(item)
+	for name := range item {
+		w.updated = append(w.updated, name)
+	}
+
+	return w, nil
+}
+
+// prepKeyed validates the parts of an update, delete or condition check operation
+func (db *DB) prepKeyed(
+	tableName *string,
+	key map[string]*dynamodb.AttributeValue,
+	condExpr *string,
+	updExpr *string,
+	names map[string]*string,
+	values map[string]*dynamodb.AttributeValue,
+) (w *write, upd *update, err error) {
+	w = &write{}
+	if w.tbl, err = db.table(tableName); err != nil {
+		return nil, nil, err
+	}
+
+	used := map[string]bool{}
+	if upd, err = parseUpdateExpr(updExpr, names, values, used); err != nil {
+		return nil, nil, err
+	}
+
+	if w.cond, err = parseCondExpr(condExpr, names, values, used); err != nil {
+		return nil, nil, err
+	}
+
+	if err = checkUnused(names, values, used); err != nil {
+		return nil, nil, err
+	}
+
+	if w.id, err = w.tbl.primaryKey(key); err != nil {
+		return nil, nil, err
+	}
+
+	w.key = copyItem(key)
+	w.old = w.tbl.items[w.id]
+	return w, upd, nil
+}
+
+// prepUpdate validates an update operation and computes the updated item
+func (db *DB) prepUpdate(
+	tableName *string,
+	key map[string]*dynamodb.AttributeValue,
+	condExpr *string,
+	updExpr *string,
+	names map[string]*string,
+	values map[string]*dynamodb.AttributeValue,
+) (w *write, err error) {
+	var upd *update
+	if w, upd, err = db.prepKeyed(tableName, key, condExpr, updExpr, names, values); err != nil {
+		return nil, err
+	}
+
+	base := w.old
+	if base == nil {
+		base = copyItem(key)
+	}
+
+	w.new = base
+	if upd != nil {
+		if w.new, w.updated, err = applyUpdate(base, upd, w.tbl.keys); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = w.tbl.validateItem(w.new); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// prepDelete validates a delete operation
+func (db *DB) prepDelete(
+	tableName *string,
+	key map[string]*dynamodb.AttributeValue,
+	condExpr *string,
+	names map[string]*string,
+	values map[string]*dynamodb.AttributeValue,
+) (w *write, err error) {
+	if w, _, err = db.prepKeyed(tableName, key, condExpr, nil, names, values); err != nil {
+		return nil, err
+	}
+
+	w.del = true
+	return w, nil
+}
+
+// prepCheck validates a condition check operation
+func (db *DB) prepCheck(
+	tableName *string,
+	key map[string]*dynamodb.AttributeValue,
+	condExpr *string,
+	names map[string]*string,
+	values map[string]*dynamodb.AttributeValue,
+) (w *write, err error) {
+	if condExpr == nil {
+		return nil, validationErrorf("The ConditionExpression must be provided for a ConditionCheck")
+	}
+
+	if w, _, err = db.prepKeyed(tableName, key, condExpr, nil, names, values); err != nil {
+		return nil, err
+	}
+
+	w.check = true
+	return w, nil
+}
+
+// runWrite checks the condition and commits a single write
+func runWrite(w *write) error {
+	ok, err := w.ok()
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return conditionFailed()
+	}
+
+	w.commit()
+	return nil
+}
+
+// PutItemWithContext creates or replaces an item
+func (db *DB) PutItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.PutItemInput,
+	opts ...request.Option,
+) (*dynamodb.PutItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := checkReturnValues(in.ReturnValues, dynamodb.ReturnValueAllOld); err != nil {
+		return nil, err
+	}
+
+	w, err := db.prepPut(in.TableName, in.Item, in.ConditionExpression,
+		in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = runWrite(w); err != nil {
+		return nil, err
+	}
+
+	c := newCapacity(w.tbl, false)
+	c.addWrite(w, 1)
+	return &dynamodb.PutItemOutput{
+		Attributes:       w.returnValues(in.ReturnValues),
+		ConsumedCapacity: c.consumed(in.ReturnConsumedCapacity),
+	}, nil
+}
+
+// UpdateItemWithContext updates, or creates, an item
+func (db *DB) UpdateItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.UpdateItemInput,
+	opts ...request.Option,
+) (*dynamodb.UpdateItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := checkReturnValues(in.ReturnValues,
+		dynamodb.ReturnValueAllOld, dynamodb.ReturnValueAllNew,
+		dynamodb.ReturnValueUpdatedOld, dynamodb.ReturnValueUpdatedNew); err != nil {
+		return nil, err
+	}
+
+	w, err := db.prepUpdate(in.TableName, in.Key, in.ConditionExpression, in.UpdateExpression,
+		in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = runWrite(w); err != nil {
+		return nil, err
+	}
+
+	c := newCapacity(w.tbl, false)
+	c.addWrite(w, 1)
+	return &dynamodb.UpdateItemOutput{
+		Attributes:       w.returnValues(in.ReturnValues),
+		ConsumedCapacity: c.consumed(in.ReturnConsumedCapacity),
+	}, nil
+}
+
+// DeleteItemWithContext deletes an item
+func (db *DB) DeleteItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.DeleteItemInput,
+	opts ...request.Option,
+) (*dynamodb.DeleteItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := checkReturnValues(in.ReturnValues, dynamodb.ReturnValueAllOld); err != nil {
+		return nil, err
+	}
+
+	w, err := db.prepDelete(in.TableName, in.Key, in.ConditionExpression,
+		in.ExpressionAttributeNames, in.ExpressionAttributeValues)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = runWrite(w); err != nil {
+		return nil, err
+	}
+
+	c := newCapacity(w.tbl, false)
+	c.addWrite(w, 1)
+	return &dynamodb.DeleteItemOutput{
+		Attributes:       w.returnValues(in.ReturnValues),
+		ConsumedCapacity: c.consumed(in.ReturnConsumedCapacity),
+	}, nil
+}
+
+// get returns the (projected) item for the key, or nil if it doesn't exist. It also returns
+// the table and the size of the whole item for capacity accounting.
+func (db *DB) get(
+	tableName *string,
+	key map[string]*dynamodb.AttributeValue,
+	projExpr *string,
+	names map[string]*string,
+) (it map[string]*dynamodb.AttributeValue, tbl *table, size int, err error) {
+	if tbl, err = db.table(tableName); err != nil {
+		return nil, nil, 0, err
+	}
+
+	used := map[string]bool{}
+	proj, err := parseProjection(projExpr, names, used)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	if err = checkUnused(names, nil, used); err != nil {
+		return nil, nil, 0, err
+	}
+
+	id, err := tbl.primaryKey(key)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	it, ok := tbl.items[id]
+	if !ok {
+		return nil, tbl, 0, nil
+	}
+
+	return project(it, proj), tbl, itemSize(it), nil
+}
+
+// GetItemWithContext reads a single item
+func (db *DB) GetItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.GetItemInput,
+	opts ...request.Option,
+) (*dynamodb.GetItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	it, tbl, size, err := db.get(in.TableName, in.Key, in.ProjectionExpression, in.ExpressionAttributeNames)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCapacity(tbl, true)
+	c.addRead(nil, size, aws.BoolValue(in.ConsistentRead), 1)
+	return &dynamodb.GetItemOutput{Item: it, ConsumedCapacity: c.consumed(in.ReturnConsumedCapacity)}, nil
+}
@@ -0,0 +1,159 @@
+// Package memddb provides an in-memory implementation of the DynamoDB operations that are used
+// by the ddb package. It allows access patterns to be tested without running DynamoDB (Local).
+// It implements key schemas, secondary indexes, all expression types, transactions and
+// pagination but does not enforce throughput limits.
+package memddb
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	// maxItemSize is the maximum size of a single item
+	maxItemSize = 400 * 1024
+
+	// maxPageSize is the maximum size of the data that is evaluated by a query or scan page
+	maxPageSize = 1024 * 1024
+
+	// idempotencyWindow is how long a ClientRequestToken is remembered
+	idempotencyWindow = 10 * time.Minute
+
+	// now returns the current time
+	now = time.Now
+)
+
+// DB is an in-memory DynamoDB. It is safe for concurrent use.
+type DB struct {
+	mu     sync.Mutex
+	tables map[string]*table
+	tokens map[string]requestToken
+}
+
+// requestToken is a ClientRequestToken that was used for a transaction
+type requestToken struct {
+	hash string
+	exp  time.Time
+}
+
+// New inits an empty in-memory DynamoDB
+func New() *DB {
+	return &DB{tables: map[string]*table{}, tokens: map[string]requestToken{}}
+}
+
+// validationErrorf returns an error that is equal to what DynamoDB returns for invalid input
+func validationErrorf(format string, args ...interface{}) error {
+	return awserr.New("ValidationException", fmt.Sprintf(format, args...), nil)
+}
+
+// table returns the named table or a ResourceNotFoundException
+func (db *DB) table(name *string) (*table, error) {
+	tbl, ok := db.tables[aws.StringValue(name)]
+	if !ok {
+		return nil, &dynamodb.ResourceNotFoundException{
+			Message_: aws.String("Requested resource not found"),
+		}
+	}
+	return tbl, nil
+}
+
+// CreateTable creates a table
+func (db *DB) CreateTable(in *dynamodb.CreateTableInput) (*dynamodb.CreateTableOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.tables[aws.StringValue(in.TableName)]; ok {
+		return nil, &dynamodb.ResourceInUseException{
+			Message_: aws.String("Cannot create preexisting table"),
+		}
+	}
+
+	tbl, err := newTable(in)
+	if err != nil {
+		return nil, err
+	}
+
+	db.tables[aws.StringValue(in.TableName)] = tbl
+	return &dynamodb.CreateTableOutput{TableDescription: tbl.description()}, nil
+}
+
+// CreateTableWithContext creates a table
+func (db *DB) CreateTableWithContext(
+	ctx aws.Context,
+	in *dynamodb.CreateTableInput,
+	opts ...request.Option,
+) (*dynamodb.CreateTableOutput, error) {
+	return db.CreateTable(in)
+}
+
+// DescribeTableWithContext describes a table
+func (db *DB) DescribeTableWithContext(
+	ctx aws.Context,
+	in *dynamodb.DescribeTableInput,
+	opts ...request.Option,
+) (*dynamodb.DescribeTableOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tbl, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.DescribeTableOutput{Table: tbl.description()}, nil
+}
+
+// DeleteTableWithContext deletes a table and all its items
+func (db *DB) DeleteTableWithContext(
+	ctx aws.Context,
+	in *dynamodb.DeleteTableInput,
+	opts ...request.Option,
+) (*dynamodb.DeleteTableOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tbl, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(db.tables, aws.StringValue(in.TableName))
+	desc := tbl.description()
+	desc.TableStatus = aws.String(dynamodb.TableStatusDeleting)
+	return &dynamodb.DeleteTableOutput{TableDescription: desc}, nil
+}
+
+// ListTablesWithContext lists the names of all tables
+func (db *DB) ListTablesWithContext(
+	ctx aws.Context,
+	in *dynamodb.ListTablesInput,
+	opts ...request.Option,
+) (*dynamodb.ListTablesOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var names []string
+	for name := range db.tables {
+		if in.ExclusiveStartTableName == nil || name > *in.ExclusiveStartTableName {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	out := &dynamodb.ListTablesOutput{}
+	limit := int(aws.Int64Value(in.Limit))
+	if limit > 0 && len(names) > limit {
+		names = names[:limit]
+		out.LastEvaluatedTableName = aws.String(names[limit-1])
+	}
+
+	out.TableNames = aws.StringSlice(names)
+	return out, nil
+}
@@ -0,0 +1,504 @@
+package memddb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// withTable returns a db with a table that has a pk/sk key and a global index on gsi1pk/gsi1sk
+func withTable(tb testing.TB) *DB {
+	db := New()
+	if _, err := db.CreateTable(&dynamodb.CreateTableInput{
+		TableName: aws.String("tbl"),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("pk"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("sk"), AttributeType: aws.String("N")},
+			{AttributeName: aws.String("gsi1pk"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("gsi1sk"), AttributeType: aws.String("S")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("pk"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("sk"), KeyType: aws.String("RANGE")},
+		},
+		GlobalSecondaryIndexes: []*dynamodb.GlobalSecondaryIndex{{
+			IndexName: aws.String("gsi1"),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String("gsi1pk"), KeyType: aws.String("HASH")},
+				{AttributeName: aws.String("gsi1sk"), KeyType: aws.String("RANGE")},
+			},
+			Projection: &dynamodb.Projection{ProjectionType: aws.String("KEYS_ONLY")},
+		}},
+		BillingMode: aws.String("PAY_PER_REQUEST"),
+	}); err != nil {
+		tb.Fatalf("failed to create table: %v", err)
+	}
+
+	return db
+}
+
+// item marshals v into an attribute value map
+func item(tb testing.TB, v interface{}) map[string]*dynamodb.AttributeValue {
+	it, err := dynamodbattribute.MarshalMap(v)
+	if err != nil {
+		tb.Fatalf("failed to marshal: %v", err)
+	}
+	return it
+}
+
+// key returns the primary key of an item in the test table
+func key(pk string, sk int) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"pk": {S: aws.String(pk)},
+		"sk": {N: aws.String(strconv.Itoa(sk))},
+	}
+}
+
+// errCode returns the aws error code of err
+func errCode(err error) string {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		return aerr.Code()
+	}
+	return ""
+}
+
+func put(tb testing.TB, db *DB, v interface{}) {
+	if _, err := db.PutItemWithContext(context.Background(), &dynamodb.PutItemInput{
+		TableName: aws.String("tbl"), Item: item(tb, v),
+	}); err != nil {
+		tb.Fatalf("failed to put: %v", err)
+	}
+}
+
+func TestPutGetDelete(t *testing.T) {
+	ctx, db := context.Background(), withTable(t)
+	put(t, db, map[string]interface{}{"pk": "a", "sk": 1, "foo": "bar", "nrs": []int{1, 2}})
+
+	out, err := db.GetItemWithContext(ctx, &dynamodb.GetItemInput{TableName: aws.String("tbl"), Key: key("a", 1)})
+	if err != nil || aws.StringValue(out.Item["foo"].S) != "bar" {
+		t.Fatalf("got: %v %v", out, err)
+	}
+
+	expr, _ := e.NewBuilder().WithProjection(e.NamesList(e.Name("nrs[1]"))).Build()
+	if out, err = db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("tbl"), Key: key("a", 1),
+		ProjectionExpression:     expr.Projection(),
+		ExpressionAttributeNames: expr.Names(),
+	}); err != nil || len(out.Item) != 1 || aws.StringValue(out.Item["nrs"].L[0].N) != "2" {
+		t.Fatalf("got: %v %v", out, err)
+	}
+
+	expr, _ = e.NewBuilder().WithProjection(e.NamesList(e.Name("nrs"), e.Name("nrs[1]"))).Build()
+	if _, err = db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("tbl"), Key: key("a", 1),
+		ProjectionExpression:     expr.Projection(),
+		ExpressionAttributeNames: expr.Names(),
+	}); errCode(err) != "ValidationException" {
+		t.Fatalf("got: %v", err)
+	}
+
+	expr, _ = e.NewBuilder().WithCondition(e.Name("foo").Equal(e.Value("baz"))).Build()
+	if _, err = db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String("tbl"), Key: key("a", 1),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}); errCode(err) != dynamodb.ErrCodeConditionalCheckFailedException {
+		t.Fatalf("got: %v", err)
+	}
+
+	del, err := db.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String("tbl"), Key: key("a", 1), ReturnValues: aws.String("ALL_OLD"),
+	})
+	if err != nil || aws.StringValue(del.Attributes["foo"].S) != "bar" {
+		t.Fatalf("got: %v %v", del, err)
+	}
+
+	if out, err = db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("tbl"), Key: key("a", 1),
+	}); err != nil || out.Item != nil {
+		t.Fatalf("got: %v %v", out, err)
+	}
+}
+
+func TestValidation(t *testing.T) {
+	ctx, db := context.Background(), withTable(t)
+	for i, in := range []*dynamodb.PutItemInput{
+		{TableName: aws.String("tbl"), Item: item(t, map[string]interface{}{"pk": "a"})},
+		{TableName: aws.String("tbl"), Item: item(t, map[string]interface{}{"pk": "a", "sk": "1"})},
+		{TableName: aws.String("tbl"), Item: item(t, map[string]interface{}{"pk": "a", "sk": 1, "gsi1pk": 1})},
+		{TableName: aws.String("tbl"), Item: key("a", 1),
+			ConditionExpression: aws.String("#a = :a"), ExpressionAttributeNames: map[string]*string{"#a": aws.String("a")}},
+		{TableName: aws.String("tbl"), Item: key("a", 1),
+			ConditionExpression: aws.String("attribute_exists(a)"), ExpressionAttributeNames: map[string]*string{"#a": aws.String("a")}},
+	} {
+		if _, err := db.PutItemWithContext(ctx, in); errCode(err) != "ValidationException" {
+			t.Fatalf("%d, got: %v", i, err)
+		}
+	}
+
+	if _, err := db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String("other"), Item: key("a", 1),
+	}); errCode(err) != dynamodb.ErrCodeResourceNotFoundException {
+		t.Fatalf("got: %v", err)
+	}
+}
+
+func TestConditions(t *testing.T) {
+	ctx, db := context.Background(), withTable(t)
+	put(t, db, map[string]interface{}{
+		"pk": "a", "sk": 1, "s": "foobar", "n": 10, "l": []string{"x", "y"},
+		"m": map[string]interface{}{"k": "v"},
+	})
+
+	for _, c := range []struct {
+		cond e.ConditionBuilder
+		exp  bool
+	}{
+		{e.AttributeExists(e.Name("s")), true},
+		{e.AttributeNotExists(e.Name("s")), false},
+		{e.Name("s").BeginsWith("foo"), true},
+		{e.Name("s").Contains("oba"), true},
+		{e.Name("l").Contains("y"), true},
+		{e.Name("m.k").Equal(e.Value("v")), true},
+		{e.Name("l[1]").Equal(e.Value("y")), true},
+		{e.Name("l[5]").Equal(e.Value("y")), false},
+		{e.Name("n").Between(e.Value(5), e.Value(10)), true},
+		{e.Name("n").GreaterThan(e.Value(9.5)), true},
+		{e.Name("n").LessThan(e.Value("x")), false},
+		{e.Name("n").In(e.Value(1), e.Value(10)), true},
+		{e.Name("s").Size().Equal(e.Value(6)), true},
+		{e.Name("l").Size().GreaterThan(e.Value(2)), false},
+		{e.Name("l").AttributeType(e.List), true},
+		{e.Name("m").AttributeType(e.StringSet), false},
+		{e.Not(e.Name("n").NotEqual(e.Value(10))), true},
+		{e.Name("x").NotEqual(e.Value(1)), true},
+		{e.Or(e.AttributeNotExists(e.Name("s")), e.Name("n").Equal(e.Value(10))), true},
+		{e.And(e.AttributeExists(e.Name("s")), e.Name("n").Equal(e.Value(11))), false},
+	} {
+		expr, err := e.NewBuilder().WithCondition(c.cond).Build()
+		if err != nil {
+			t.Fatalf("failed to build: %v", err)
+		}
+
+		_, err = db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String("tbl"), Key: key("a", 1),
+			ConditionExpression:       expr.Condition(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+		})
+
+		if act := err == nil; act != c.exp {
+			t.Fatalf("%s: expected %v, got: %v", aws.StringValue(expr.Condition()), c.exp, err)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx, db := context.Background(), withTable(t)
+	put(t, db, map[string]interface{}{
+		"pk": "a", "sk": 1, "n": 10, "l": []string{"x"}, "m": map[string]interface{}{"k": "v"}, "r": 1,
+	})
+
+	expr, _ := e.NewBuilder().WithUpdate(e.
+		Set(e.Name("n"), e.Name("n").Plus(e.Value(5))).
+		Set(e.Name("l"), e.ListAppend(e.Name("l"), e.Value([]string{"y"}))).
+		Set(e.Name("m.k2"), e.Value("v2")).
+		Set(e.Name("c"), e.IfNotExists(e.Name("c"), e.Value(0))).
+		Add(e.Name("ns"), e.Value(&dynamodb.AttributeValue{NS: aws.StringSlice([]string{"1", "2"})})).
+		Remove(e.Name("r"))).Build()
+
+	out, err := db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String("tbl"), Key: key("a", 1),
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnValues:              aws.String("UPDATED_NEW"),
+	})
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	var act map[string]interface{}
+	if err = dynamodbattribute.UnmarshalMap(out.Attributes, &act); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	exp := map[string]interface{}{
+		"n": 15.0, "l": []interface{}{"x", "y"}, "m": map[string]interface{}{"k": "v", "k2": "v2"}, "c": 0.0,
+		"ns": []float64{1, 2},
+	}
+	if !reflect.DeepEqual(act, exp) {
+		t.Fatalf("got: %v", act)
+	}
+
+	expr, _ = e.NewBuilder().WithUpdate(e.Set(e.Name("pk"), e.Value("b"))).Build()
+	if _, err = db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String("tbl"), Key: key("a", 1),
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}); errCode(err) != "ValidationException" || !strings.Contains(err.Error(), "part of the key") {
+		t.Fatalf("got: %v", err)
+	}
+
+	expr, _ = e.NewBuilder().WithUpdate(e.Set(e.Name("m"), e.Value(1)).Set(e.Name("m.k"), e.Value(2))).Build()
+	if _, err = db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String("tbl"), Key: key("a", 1),
+		UpdateExpression:          expr.Update(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}); errCode(err) != "ValidationException" {
+		t.Fatalf("got: %v", err)
+	}
+
+	// updating an item that doesn't exist creates it
+	if _, err = db.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String("tbl"), Key: key("b", 1),
+	}); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+}
+
+func TestQueryScan(t *testing.T) {
+	ctx, db := context.Background(), withTable(t)
+	for i := 0; i < 10; i++ {
+		put(t, db, map[string]interface{}{"pk": "a", "sk": i, "gsi1pk": "g", "gsi1sk": strconv.Itoa(9 - i), "x": i % 2})
+		put(t, db, map[string]interface{}{"pk": "b", "sk": i})
+	}
+
+	expr, _ := e.NewBuilder().
+		WithKeyCondition(e.Key("pk").Equal(e.Value("a")).And(e.Key("sk").Between(e.Value(2), e.Value(7)))).
+		WithFilter(e.Name("x").Equal(e.Value(1))).
+		Build()
+
+	var sks []string
+	var lek map[string]*dynamodb.AttributeValue
+	var pages int
+	for {
+		out, err := db.QueryWithContext(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String("tbl"),
+			KeyConditionExpression:    expr.KeyCondition(),
+			FilterExpression:          expr.Filter(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			ScanIndexForward:          aws.Bool(false),
+			ExclusiveStartKey:         lek,
+			Limit:                     aws.Int64(2),
+		})
+		if err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		pages++
+		for _, it := range out.Items {
+			sks = append(sks, aws.StringValue(it["sk"].N))
+		}
+
+		if lek = out.LastEvaluatedKey; lek == nil {
+			break
+		}
+	}
+
+	if act := strings.Join(sks, ","); act != "7,5,3" || pages != 4 {
+		t.Fatalf("got: %v %d", act, pages)
+	}
+
+	expr, _ = e.NewBuilder().WithKeyCondition(e.Key("gsi1pk").Equal(e.Value("g")).
+		And(e.Key("gsi1sk").BeginsWith("1"))).Build()
+	out, err := db.QueryWithContext(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String("tbl"),
+		IndexName:                 aws.String("gsi1"),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	})
+	if err != nil || len(out.Items) != 1 || len(out.Items[0]) != 4 || aws.StringValue(out.Items[0]["sk"].N) != "8" {
+		t.Fatalf("got: %v %v", out, err)
+	}
+
+	if _, err = db.QueryWithContext(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String("tbl"),
+		IndexName:                 aws.String("gsi1"),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ConsistentRead:            aws.Bool(true),
+	}); errCode(err) != "ValidationException" {
+		t.Fatalf("got: %v", err)
+	}
+
+	cnt, err := db.ScanWithContext(ctx, &dynamodb.ScanInput{TableName: aws.String("tbl"), Select: aws.String("COUNT")})
+	if err != nil || aws.Int64Value(cnt.Count) != 20 || cnt.Items != nil {
+		t.Fatalf("got: %v %v", cnt, err)
+	}
+
+	var total int64
+	for seg := int64(0); seg < 3; seg++ {
+		out, err := db.ScanWithContext(ctx, &dynamodb.ScanInput{
+			TableName: aws.String("tbl"), Segment: aws.Int64(seg), TotalSegments: aws.Int64(3),
+		})
+		if err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		total += aws.Int64Value(out.Count)
+	}
+
+	if total != 20 {
+		t.Fatalf("got: %d", total)
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	ctx, db := context.Background(), withTable(t)
+	put(t, db, map[string]interface{}{"pk": "a", "sk": 1, "n": 1})
+
+	cond, _ := e.NewBuilder().WithCondition(e.Name("n").Equal(e.Value(2))).Build()
+	in := &dynamodb.TransactWriteItemsInput{TransactItems: []*dynamodb.TransactWriteItem{
+		{Put: &dynamodb.Put{TableName: aws.String("tbl"), Item: key("b", 1)}},
+		{ConditionCheck: &dynamodb.ConditionCheck{
+			TableName: aws.String("tbl"), Key: key("a", 1),
+			ConditionExpression:                 cond.Condition(),
+			ExpressionAttributeNames:            cond.Names(),
+			ExpressionAttributeValues:           cond.Values(),
+			ReturnValuesOnConditionCheckFailure: aws.String("ALL_OLD"),
+		}},
+	}}
+
+	_, err := db.TransactWriteItemsWithContext(ctx, in)
+	var tcerr *dynamodb.TransactionCanceledException
+	if !errors.As(err, &tcerr) || len(tcerr.CancellationReasons) != 2 ||
+		aws.StringValue(tcerr.CancellationReasons[0].Code) != "None" ||
+		aws.StringValue(tcerr.CancellationReasons[1].Code) != "ConditionalCheckFailed" ||
+		aws.StringValue(tcerr.CancellationReasons[1].Item["n"].N) != "1" {
+		t.Fatalf("got: %v", err)
+	}
+
+	get := &dynamodb.TransactGetItemsInput{TransactItems: []*dynamodb.TransactGetItem{
+		{Get: &dynamodb.Get{TableName: aws.String("tbl"), Key: key("b", 1)}},
+		{Get: &dynamodb.Get{TableName: aws.String("tbl"), Key: key("a", 1)}},
+	}}
+
+	out, err := db.TransactGetItemsWithContext(ctx, get)
+	if err != nil || out.Responses[0].Item != nil || out.Responses[1].Item == nil {
+		t.Fatalf("got: %v %v", out, err)
+	}
+
+	put(t, db, map[string]interface{}{"pk": "a", "sk": 1, "n": 2})
+	in.ClientRequestToken = aws.String("tok")
+	if _, err = db.TransactWriteItemsWithContext(ctx, in); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if out, err = db.TransactGetItemsWithContext(ctx, get); err != nil || out.Responses[0].Item == nil {
+		t.Fatalf("got: %v %v", out, err)
+	}
+
+	// retrying with the same token doesn't apply the writes again
+	put(t, db, map[string]interface{}{"pk": "a", "sk": 1, "n": 3})
+	if _, err = db.TransactWriteItemsWithContext(ctx, in); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	in.TransactItems = in.TransactItems[:1]
+	if _, err = db.TransactWriteItemsWithContext(ctx, in); errCode(err) != dynamodb.ErrCodeIdempotentParameterMismatchException {
+		t.Fatalf("got: %v", err)
+	}
+
+	in.ClientRequestToken = nil
+	in.TransactItems = append(in.TransactItems, in.TransactItems[0])
+	if _, err = db.TransactWriteItemsWithContext(ctx, in); errCode(err) != "ValidationException" {
+		t.Fatalf("got: %v", err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	ctx, db := context.Background(), withTable(t)
+
+	var wrs []*dynamodb.WriteRequest
+	for i := 0; i < 25; i++ {
+		wrs = append(wrs, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: key("a", i)}})
+	}
+
+	if _, err := db.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]*dynamodb.WriteRequest{"tbl": append(wrs, wrs[0])},
+	}); errCode(err) != "ValidationException" {
+		t.Fatalf("got: %v", err)
+	}
+
+	if _, err := db.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{
+		RequestItems: map[string][]*dynamodb.WriteRequest{"tbl": wrs},
+	}); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	out, err := db.BatchGetItemWithContext(ctx, &dynamodb.BatchGetItemInput{
+		RequestItems: map[string]*dynamodb.KeysAndAttributes{"tbl": {
+			Keys: []map[string]*dynamodb.AttributeValue{key("a", 1), key("a", 24), key("a", 25)},
+		}},
+	})
+	if err != nil || len(out.Responses["tbl"]) != 2 {
+		t.Fatalf("got: %v %v", out, err)
+	}
+}
+
+func TestConsumedCapacity(t *testing.T) {
+	ctx, db := context.Background(), withTable(t)
+
+	pout, err := db.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName:              aws.String("tbl"),
+		Item:                   item(t, map[string]interface{}{"pk": "a", "sk": 1, "gsi1pk": "g", "gsi1sk": "s"}),
+		ReturnConsumedCapacity: aws.String("INDEXES"),
+	})
+	if cc := pout.ConsumedCapacity; err != nil || aws.Float64Value(cc.CapacityUnits) != 2 ||
+		aws.Float64Value(cc.Table.CapacityUnits) != 1 ||
+		aws.Float64Value(cc.GlobalSecondaryIndexes["gsi1"].WriteCapacityUnits) != 1 {
+		t.Fatalf("got: %v %v", pout, err)
+	}
+
+	tout, err := db.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Put: &dynamodb.Put{TableName: aws.String("tbl"), Item: key("b", 1)}},
+			{Put: &dynamodb.Put{TableName: aws.String("tbl"), Item: key("c", 1)}},
+		},
+		ReturnConsumedCapacity: aws.String("TOTAL"),
+	})
+	if err != nil || len(tout.ConsumedCapacity) != 1 || tout.ConsumedCapacity[0].Table != nil ||
+		aws.Float64Value(tout.ConsumedCapacity[0].CapacityUnits) != 4 {
+		t.Fatalf("got: %v %v", tout, err)
+	}
+
+	gout, err := db.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String("tbl"), Key: key("a", 1), ConsistentRead: aws.Bool(true),
+		ReturnConsumedCapacity: aws.String("INDEXES"),
+	})
+	if err != nil || aws.Float64Value(gout.ConsumedCapacity.ReadCapacityUnits) != 1 {
+		t.Fatalf("got: %v %v", gout, err)
+	}
+
+	expr, _ := e.NewBuilder().WithKeyCondition(e.Key("gsi1pk").Equal(e.Value("g"))).Build()
+	qout, err := db.QueryWithContext(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String("tbl"),
+		IndexName:                 aws.String("gsi1"),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ReturnConsumedCapacity:    aws.String("INDEXES"),
+	})
+	if cc := qout.ConsumedCapacity; err != nil || aws.Float64Value(cc.CapacityUnits) != 0.5 ||
+		aws.Float64Value(cc.Table.CapacityUnits) != 0 ||
+		aws.Float64Value(cc.GlobalSecondaryIndexes["gsi1"].ReadCapacityUnits) != 0.5 {
+		t.Fatalf("got: %v %v", qout, err)
+	}
+}
@@ -0,0 +1,422 @@
+package memddb
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// page holds the result of reading one page of a query or scan
+type page struct {
+	items   []map[string]*dynamodb.AttributeValue
+	count   int64
+	scanned int64
+	size    int
+	lek     map[string]*dynamodb.AttributeValue
+}
+
+// readPage evaluates the (ordered) items, starting after 'start', until the limit or the
+// maximum page size is reached.
+func (v *view) readPage(
+	items []map[string]*dynamodb.AttributeValue,
+	start map[string]*dynamodb.AttributeValue,
+	forward bool,
+	limit int64,
+	filter cond,
+	proj []path,
+	countOnly bool,
+) (p *page, err error) {
+	p = &page{}
+	if start != nil {
+		for _, n := range v.keyNames() {
+			if _, ok := start[n]; !ok {
+				return nil, validationErrorf("The provided starting key is invalid: The provided key element does not match the schema")
+			}
+		}
+	}
+
+	for _, it := range items {
+		if start != nil {
+			c := v.compare(it, start)
+			if (forward && c <= 0) || (!forward && c >= 0) {
+				continue
+			}
+		}
+
+		p.scanned++
+		p.size += itemSize(it)
+
+		var ok bool
+		if ok, err = evalCond(it, filter); err != nil {
+			return nil, err
+		}
+
+		if ok {
+			p.count++
+			if !countOnly {
+				p.items = append(p.items, project(it, proj))
+			}
+		}
+
+		if (limit > 0 && p.scanned >= limit) || p.size >= maxPageSize {
+			p.lek = v.positionKey(it)
+			break
+		}
+	}
+
+	return p, nil
+}
+
+// checkSelect validates the Select parameter and returns whether only a count is asked for. Without
+// it all (projected) attributes are returned.
+func checkSelect(sel *string, v *view, proj []path) (countOnly bool, err error) {
+	switch aws.StringValue(sel) {
+	case "":
+	case dynamodb.SelectAllAttributes:
+		if v.index != nil && v.index.projType != dynamodb.ProjectionTypeAll {
+			return false, validationErrorf("One or more parameter values were invalid: Select type ALL_ATTRIBUTES is not supported for global secondary index %s because its projection type is not ALL", v.index.name)
+		}
+	case dynamodb.SelectAllProjectedAttributes:
+		if v.index == nil {
+			return false, validationErrorf("One or more parameter values were invalid: Select type ALL_PROJECTED_ATTRIBUTES is supported only for index queries")
+		}
+	case dynamodb.SelectSpecificAttributes:
+		if proj == nil {
+			return false, validationErrorf("One or more parameter values were invalid: Select type SPECIFIC_ATTRIBUTES requires a ProjectionExpression")
+		}
+	case dynamodb.SelectCount:
+		if proj != nil {
+			return false, validationErrorf("One or more parameter values were invalid: Cannot specify the ProjectionExpression when choosing to get a COUNT")
+		}
+		return true, nil
+	default:
+		return false, validationErrorf("Select is not supported: %s", aws.StringValue(sel))
+	}
+
+	return false, nil
+}
+
+// keyCondition validates the key condition and splits it in the partition key value and the
+// condition on the sort key.
+func keyCondition(c cond, keys keySchema) (pk *dynamodb.AttributeValue, sk cond, err error) {
+	parts := []cond{c}
+	if and, ok := c.(*andCond); ok {
+		parts = []cond{and.l, and.r}
+	}
+
+	for _, part := range parts {
+		name, ok := keyCondName(part)
+		switch {
+		case !ok:
+			return nil, nil, validationErrorf("Invalid KeyConditionExpression: Query key condition not supported")
+		case name == keys.pk && pk == nil:
+			cc, isCmp := part.(*compareCond)
+			if !isCmp || cc.op != "=" {
+				return nil, nil, validationErrorf("Query key condition not supported")
+			}
+
+			if vo, isVal := cc.r.(*valueOperand); isVal {
+				pk = vo.av
+			} else {
+				pk = cc.l.(*valueOperand).av
+			}
+		case name == keys.sk && keys.sk != "" && sk == nil:
+			sk = part
+		default:
+			return nil, nil, validationErrorf("Query condition missed key schema element: %s", keys.pk)
+		}
+	}
+
+	if pk == nil {
+		return nil, nil, validationErrorf("Query condition missed key schema element: %s", keys.pk)
+	}
+
+	return pk, sk, nil
+}
+
+// keyCondName returns the key attribute a single part of a key condition applies to
+func keyCondName(c cond) (string, bool) {
+	pathName := func(o operand) (string, bool) {
+		po, ok := o.(*pathOperand)
+		if !ok || len(po.p) != 1 {
+			return "", false
+		}
+		return po.p[0].name, true
+	}
+
+	isValue := func(o operand) bool {
+		_, ok := o.(*valueOperand)
+		return ok
+	}
+
+	switch c := c.(type) {
+	case *compareCond:
+		if c.op == "<>" {
+			return "", false
+		}
+
+		if n, ok := pathName(c.l); ok && isValue(c.r) {
+			return n, true
+		}
+
+		if n, ok := pathName(c.r); ok && isValue(c.l) && c.op == "=" {
+			return n, true
+		}
+	case *betweenCond:
+		if n, ok := pathName(c.v); ok && isValue(c.lo) && isValue(c.hi) {
+			return n, true
+		}
+	case *funcCond:
+		if c.name == "begins_with" && isValue(c.args[1]) {
+			return pathName(c.args[0])
+		}
+	}
+
+	return "", false
+}
+
+// checkFilterKeys makes sure a query filter doesn't refer to the key attributes
+func checkFilterKeys(c cond, keys keySchema) error {
+	var paths []path
+	var walkOperand func(o operand)
+	walkOperand = func(o operand) {
+		switch o := o.(type) {
+		case *pathOperand:
+			paths = append(paths, o.p)
+		case *sizeOperand:
+			paths = append(paths, o.p)
+		}
+	}
+
+	var walk func(c cond)
+	walk = func(c cond) {
+		switch c := c.(type) {
+		case *andCond:
+			walk(c.l)
+			walk(c.r)
+		case *orCond:
+			walk(c.l)
+			walk(c.r)
+		case *notCond:
+			walk(c.c)
+		case *compareCond:
+			walkOperand(c.l)
+			walkOperand(c.r)
+		case *betweenCond:
+			walkOperand(c.v)
+			walkOperand(c.lo)
+			walkOperand(c.hi)
+		case *inCond:
+			walkOperand(c.v)
+			for _, o := range c.list {
+				walkOperand(o)
+			}
+		case *funcCond:
+			for _, o := range c.args {
+				walkOperand(o)
+			}
+		}
+	}
+
+	walk(c)
+	for _, p := range paths {
+		for _, n := range keys.names() {
+			if p[0].name == n {
+				return validationErrorf("Filter Expression can only contain non-primary key attributes: Primary key attribute: %s", n)
+			}
+		}
+	}
+
+	return nil
+}
+
+// QueryWithContext reads the items of one partition of a table or index
+func (db *DB) QueryWithContext(
+	ctx aws.Context,
+	in *dynamodb.QueryInput,
+	opts ...request.Option,
+) (*dynamodb.QueryOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tbl, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := tbl.view(in.IndexName)
+	if err != nil {
+		return nil, err
+	}
+
+	if aws.BoolValue(in.ConsistentRead) && v.index != nil && v.index.global {
+		return nil, validationErrorf("Consistent reads are not supported on global secondary indexes")
+	}
+
+	if in.KeyConditionExpression == nil {
+		return nil, validationErrorf("Either the KeyConditions or KeyConditionExpression parameter must be specified in the request.")
+	}
+
+	used := map[string]bool{}
+	kc, err := parseCondExpr(in.KeyConditionExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues, used)
+	if err != nil {
+		return nil, err
+	}
+
+	filter, err := parseCondExpr(in.FilterExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues, used)
+	if err != nil {
+		return nil, err
+	}
+
+	proj, err := parseProjection(in.ProjectionExpression, in.ExpressionAttributeNames, used)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkUnused(in.ExpressionAttributeNames, in.ExpressionAttributeValues, used); err != nil {
+		return nil, err
+	}
+
+	pk, sk, err := keyCondition(kc, v.keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkFilterKeys(filter, v.keys); err != nil {
+		return nil, err
+	}
+
+	countOnly, err := checkSelect(in.Select, v, proj)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []map[string]*dynamodb.AttributeValue
+	for _, it := range v.items() {
+		if !equalAV(it[v.keys.pk], pk) {
+			continue
+		}
+
+		var ok bool
+		if ok, err = evalCond(it, sk); err != nil {
+			return nil, err
+		} else if ok {
+			items = append(items, it)
+		}
+	}
+
+	forward := in.ScanIndexForward == nil || *in.ScanIndexForward
+	if !forward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	p, err := v.readPage(items, in.ExclusiveStartKey, forward, aws.Int64Value(in.Limit), filter, proj, countOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCapacity(tbl, true)
+	c.addRead(v.index, p.size, aws.BoolValue(in.ConsistentRead), 1)
+	out := &dynamodb.QueryOutput{
+		Count:            aws.Int64(p.count),
+		ScannedCount:     aws.Int64(p.scanned),
+		LastEvaluatedKey: p.lek,
+		ConsumedCapacity: c.consumed(in.ReturnConsumedCapacity),
+	}
+
+	if !countOnly {
+		out.Items = p.items
+		if out.Items == nil {
+			out.Items = []map[string]*dynamodb.AttributeValue{}
+		}
+	}
+
+	return out, nil
+}
+
+// ScanWithContext reads all items of a table or index, or of a segment of it
+func (db *DB) ScanWithContext(
+	ctx aws.Context,
+	in *dynamodb.ScanInput,
+	opts ...request.Option,
+) (*dynamodb.ScanOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	tbl, err := db.table(in.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := tbl.view(in.IndexName)
+	if err != nil {
+		return nil, err
+	}
+
+	if aws.BoolValue(in.ConsistentRead) && v.index != nil && v.index.global {
+		return nil, validationErrorf("Consistent reads are not supported on global secondary indexes")
+	}
+
+	if (in.Segment == nil) != (in.TotalSegments == nil) {
+		return nil, validationErrorf("The TotalSegments parameter is required but was not present in the request when Segment parameter is present")
+	}
+
+	if in.TotalSegments != nil && (*in.TotalSegments < 1 || *in.TotalSegments > 1000000 ||
+		*in.Segment < 0 || *in.Segment >= *in.TotalSegments) {
+		return nil, validationErrorf("The Segment parameter is zero-based and must be less than parameter TotalSegments")
+	}
+
+	used := map[string]bool{}
+	filter, err := parseCondExpr(in.FilterExpression, in.ExpressionAttributeNames, in.ExpressionAttributeValues, used)
+	if err != nil {
+		return nil, err
+	}
+
+	proj, err := parseProjection(in.ProjectionExpression, in.ExpressionAttributeNames, used)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkUnused(in.ExpressionAttributeNames, in.ExpressionAttributeValues, used); err != nil {
+		return nil, err
+	}
+
+	countOnly, err := checkSelect(in.Select, v, proj)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []map[string]*dynamodb.AttributeValue
+	for _, it := range v.items() {
+		if in.TotalSegments != nil && segmentOf(it[v.keys.pk], *in.TotalSegments) != *in.Segment {
+			continue
+		}
+
+		items = append(items, it)
+	}
+
+	p, err := v.readPage(items, in.ExclusiveStartKey, true, aws.Int64Value(in.Limit), filter, proj, countOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newCapacity(tbl, true)
+	c.addRead(v.index, p.size, aws.BoolValue(in.ConsistentRead), 1)
+	out := &dynamodb.ScanOutput{
+		Count:            aws.Int64(p.count),
+		ScannedCount:     aws.Int64(p.scanned),
+		LastEvaluatedKey: p.lek,
+		ConsumedCapacity: c.consumed(in.ReturnConsumedCapacity),
+	}
+
+	if !countOnly {
+		out.Items = p.items
+		if out.Items == nil {
+			out.Items = []map[string]*dynamodb.AttributeValue{}
+		}
+	}
+
+	return out, nil
+}
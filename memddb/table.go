@@ -0,0 +1,405 @@
+package memddb
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// keySchema names the partition and (optional) sort key attributes
+type keySchema struct {
+	pk, sk string
+}
+
+// names returns the key attribute names
+func (ks keySchema) names() []string {
+	if ks.sk == "" {
+		return []string{ks.pk}
+	}
+	return []string{ks.pk, ks.sk}
+}
+
+// index describes a secondary index
+type index struct {
+	name     string
+	keys     keySchema
+	global   bool
+	projType string
+	nonKey   []string
+}
+
+// table holds the schema and the items of one table
+type table struct {
+	desc    *dynamodb.TableDescription
+	keys    keySchema
+	types   map[string]string
+	indexes map[string]*index
+	items   map[string]map[string]*dynamodb.AttributeValue
+}
+
+// newTable validates the create table input and builds the table
+func newTable(in *dynamodb.CreateTableInput) (tbl *table, err error) {
+	if aws.StringValue(in.TableName) == "" {
+		return nil, validationErrorf("1 validation error detected: Value null at 'tableName' failed to satisfy constraint: Member must not be null")
+	}
+
+	tbl = &table{
+		types:   map[string]string{},
+		indexes: map[string]*index{},
+		items:   map[string]map[string]*dynamodb.AttributeValue{},
+	}
+
+	for _, def := range in.AttributeDefinitions {
+		tbl.types[aws.StringValue(def.AttributeName)] = aws.StringValue(def.AttributeType)
+	}
+
+	if tbl.keys, err = tbl.parseKeySchema(in.KeySchema); err != nil {
+		return nil, err
+	}
+
+	used := map[string]bool{}
+	for _, n := range tbl.keys.names() {
+		used[n] = true
+	}
+
+	for _, gsi := range in.GlobalSecondaryIndexes {
+		idx := &index{name: aws.StringValue(gsi.IndexName), global: true}
+		if idx.keys, err = tbl.parseKeySchema(gsi.KeySchema); err != nil {
+			return nil, err
+		}
+
+		idx.projType, idx.nonKey = parseProjectionType(gsi.Projection)
+		for _, n := range idx.keys.names() {
+			used[n] = true
+		}
+
+		tbl.indexes[idx.name] = idx
+	}
+
+	for _, lsi := range in.LocalSecondaryIndexes {
+		idx := &index{name: aws.StringValue(lsi.IndexName)}
+		if idx.keys, err = tbl.parseKeySchema(lsi.KeySchema); err != nil {
+			return nil, err
+		}
+
+		if idx.keys.pk != tbl.keys.pk || idx.keys.sk == "" {
+			return nil, validationErrorf("One or more parameter values were invalid: Table KeySchema does not have a range key, which is required when specifying a LocalSecondaryIndex")
+		}
+
+		idx.projType, idx.nonKey = parseProjectionType(lsi.Projection)
+		used[idx.keys.sk] = true
+		tbl.indexes[idx.name] = idx
+	}
+
+	for name := range tbl.types {
+		if !used[name] {
+			return nil, validationErrorf("One or more parameter values were invalid: Some AttributeDefinitions are not used. AttributeDefinitions: [%s]", name)
+		}
+	}
+
+	tbl.desc = describe(in)
+	return tbl, nil
+}
+
+// parseKeySchema reads a key schema and checks that its attributes are defined
+func (tbl *table) parseKeySchema(elems []*dynamodb.KeySchemaElement) (ks keySchema, err error) {
+	for _, e := range elems {
+		name := aws.StringValue(e.AttributeName)
+		switch typ := tbl.types[name]; {
+		case typ == "":
+			return ks, validationErrorf("One or more parameter values were invalid: Some index key attributes are not defined in AttributeDefinitions. Keys: [%s]", name)
+		case typ != typeS && typ != typeN && typ != typeB:
+			return ks, validationErrorf("Member must satisfy enum value set: [B, N, S]")
+		}
+
+		switch aws.StringValue(e.KeyType) {
+		case dynamodb.KeyTypeHash:
+			ks.pk = name
+		case dynamodb.KeyTypeRange:
+			ks.sk = name
+		}
+	}
+
+	if ks.pk == "" {
+		return ks, validationErrorf("1 validation error detected: Value null at 'keySchema' failed to satisfy constraint: Member must not be null")
+	}
+
+	return ks, nil
+}
+
+// parseProjectionType reads an index projection
+func parseProjectionType(p *dynamodb.Projection) (typ string, nonKey []string) {
+	if p == nil || p.ProjectionType == nil {
+		return dynamodb.ProjectionTypeAll, nil
+	}
+
+	return aws.StringValue(p.ProjectionType), aws.StringValueSlice(p.NonKeyAttributes)
+}
+
+// describe builds the table description that is returned by the control plane operations
+func describe(in *dynamodb.CreateTableInput) *dynamodb.TableDescription {
+	desc := &dynamodb.TableDescription{
+		TableName:            in.TableName,
+		TableStatus:          aws.String(dynamodb.TableStatusActive),
+		TableArn:             aws.String("arn:aws:dynamodb:local:000000000000:table/" + aws.StringValue(in.TableName)),
+		KeySchema:            in.KeySchema,
+		AttributeDefinitions: in.AttributeDefinitions,
+		CreationDateTime:     aws.Time(now()),
+		ItemCount:            aws.Int64(0),
+		TableSizeBytes:       aws.Int64(0),
+		StreamSpecification:  in.StreamSpecification,
+	}
+
+	if aws.StringValue(in.BillingMode) == dynamodb.BillingModePayPerRequest {
+		desc.BillingModeSummary = &dynamodb.BillingModeSummary{BillingMode: in.BillingMode}
+	}
+
+	if in.ProvisionedThroughput != nil {
+		desc.ProvisionedThroughput = &dynamodb.ProvisionedThroughputDescription{
+			ReadCapacityUnits:  in.ProvisionedThroughput.ReadCapacityUnits,
+			WriteCapacityUnits: in.ProvisionedThroughput.WriteCapacityUnits,
+		}
+	}
+
+	for _, gsi := range in.GlobalSecondaryIndexes {
+		gd := &dynamodb.GlobalSecondaryIndexDescription{
+			IndexName:   gsi.IndexName,
+			IndexStatus: aws.String(dynamodb.IndexStatusActive),
+			IndexArn:    aws.String(aws.StringValue(desc.TableArn) + "/index/" + aws.StringValue(gsi.IndexName)),
+			KeySchema:   gsi.KeySchema,
+			Projection:  gsi.Projection,
+			ItemCount:   aws.Int64(0),
+		}
+
+		if gsi.ProvisionedThroughput != nil {
+			gd.ProvisionedThroughput = &dynamodb.ProvisionedThroughputDescription{
+				ReadCapacityUnits:  gsi.ProvisionedThroughput.ReadCapacityUnits,
+				WriteCapacityUnits: gsi.ProvisionedThroughput.WriteCapacityUnits,
+			}
+		}
+
+		desc.GlobalSecondaryIndexes = append(desc.GlobalSecondaryIndexes, gd)
+	}
+
+	for _, lsi := range in.LocalSecondaryIndexes {
+		desc.LocalSecondaryIndexes = append(desc.LocalSecondaryIndexes, &dynamodb.LocalSecondaryIndexDescription{
+			IndexName:  lsi.IndexName,
+			IndexArn:   aws.String(aws.StringValue(desc.TableArn) + "/index/" + aws.StringValue(lsi.IndexName)),
+			KeySchema:  lsi.KeySchema,
+			Projection: lsi.Projection,
+			ItemCount:  aws.Int64(0),
+		})
+	}
+
+	return desc
+}
+
+// description returns the table description with up-to-date counts
+func (tbl *table) description() *dynamodb.TableDescription {
+	desc := *tbl.desc
+	desc.ItemCount = aws.Int64(int64(len(tbl.items)))
+
+	var size int
+	for _, it := range tbl.items {
+		size += itemSize(it)
+	}
+
+	desc.TableSizeBytes = aws.Int64(int64(size))
+	return &desc
+}
+
+// primaryKey validates that 'key' holds exactly the key attributes of the table and returns
+// its identifier in the item map.
+func (tbl *table) primaryKey(key map[string]*dynamodb.AttributeValue) (string, error) {
+	if len(key) != len(tbl.keys.names()) {
+		return "", validationErrorf("The provided key element does not match the schema")
+	}
+
+	return tbl.keyID(key)
+}
+
+// keyID validates the key attributes in 'item' and returns an identifier for the item
+func (tbl *table) keyID(item map[string]*dynamodb.AttributeValue) (string, error) {
+	var id bytes.Buffer
+	for _, name := range tbl.keys.names() {
+		av := item[name]
+		if av == nil {
+			return "", validationErrorf("One or more parameter values were invalid: Missing the key %s in the item", name)
+		}
+
+		if typeOf(av) != tbl.types[name] {
+			return "", validationErrorf("One or more parameter values were invalid: Type mismatch for key %s expected: %s actual: %s", name, tbl.types[name], typeOf(av))
+		}
+
+		if len(keyBytes(av)) == 0 {
+			return "", validationErrorf("One or more parameter values are not valid. The AttributeValue for a key attribute cannot contain an empty string value. Key: %s", name)
+		}
+
+		b := keyBytes(av)
+		binary.Write(&id, binary.BigEndian, uint32(len(b)))
+		id.Write(b)
+	}
+
+	return id.String(), nil
+}
+
+// validateItem checks an item that is about to be stored
+func (tbl *table) validateItem(item map[string]*dynamodb.AttributeValue) error {
+	for _, av := range item {
+		if err := validateAV(av); err != nil {
+			return err
+		}
+	}
+
+	for _, idx := range tbl.indexes {
+		for _, name := range idx.keys.names() {
+			av, ok := item[name]
+			if !ok {
+				continue
+			}
+
+			if typeOf(av) != tbl.types[name] {
+				return validationErrorf("One or more parameter values were invalid: Type mismatch for Index Key %s Expected: %s Actual: %s IndexName: %s", name, tbl.types[name], typeOf(av), idx.name)
+			}
+
+			if len(keyBytes(av)) == 0 {
+				return validationErrorf("One or more parameter values are not valid. A value specified for a secondary index key is not supported. The AttributeValue for a key attribute cannot contain an empty string value. IndexName: %s, IndexKey: %s", idx.name, name)
+			}
+		}
+	}
+
+	if itemSize(item) > maxItemSize {
+		return validationErrorf("Item size has exceeded the maximum allowed size")
+	}
+
+	return nil
+}
+
+// view returns the keys and order of a table or one of its indexes
+type view struct {
+	keys  keySchema
+	index *index
+	tbl   *table
+}
+
+// view returns the view for the table or the named index
+func (tbl *table) view(name *string) (v *view, err error) {
+	v = &view{keys: tbl.keys, tbl: tbl}
+	if name == nil {
+		return v, nil
+	}
+
+	idx, ok := tbl.indexes[aws.StringValue(name)]
+	if !ok {
+		return nil, validationErrorf("The table does not have the specified index: %s", aws.StringValue(name))
+	}
+
+	v.index, v.keys = idx, idx.keys
+	return v, nil
+}
+
+// keyNames returns the names of all attributes that make up a position in this view
+func (v *view) keyNames() (names []string) {
+	names = v.keys.names()
+	if v.index != nil {
+		for _, n := range v.tbl.keys.names() {
+			if n != v.keys.pk && n != v.keys.sk {
+				names = append(names, n)
+			}
+		}
+	}
+	return
+}
+
+// items returns the items in the view, in their order. The items in an index are only those
+// that have the index keys and are projected according to the index projection.
+func (v *view) items() (items []map[string]*dynamodb.AttributeValue) {
+	for _, it := range v.tbl.items {
+		if v.index != nil {
+			if _, ok := it[v.keys.pk]; !ok {
+				continue
+			}
+
+			if _, ok := it[v.keys.sk]; v.keys.sk != "" && !ok {
+				continue
+			}
+
+			it = v.projectIndex(it)
+		}
+
+		items = append(items, it)
+	}
+
+	sort.Slice(items, func(i, j int) bool { return v.compare(items[i], items[j]) < 0 })
+	return
+}
+
+// projectIndex applies the index projection to an item
+func (v *view) projectIndex(it map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	if v.index.projType == dynamodb.ProjectionTypeAll {
+		return it
+	}
+
+	names := append(v.keyNames(), v.index.nonKey...)
+	out := map[string]*dynamodb.AttributeValue{}
+	for _, n := range names {
+		if av, ok := it[n]; ok {
+			out[n] = av
+		}
+	}
+	return out
+}
+
+// compare orders two items (or positions) in the view: first by the hash of the partition key,
+// then by the sort key and, for indexes, by the table's key.
+func (v *view) compare(a, b map[string]*dynamodb.AttributeValue) int {
+	if c := bytes.Compare(partitionHash(a[v.keys.pk]), partitionHash(b[v.keys.pk])); c != 0 {
+		return c
+	}
+
+	if c := bytes.Compare(keyBytes(a[v.keys.pk]), keyBytes(b[v.keys.pk])); c != 0 {
+		return c
+	}
+
+	for _, name := range v.keyNames()[1:] {
+		if c, ok := compareAV(a[name], b[name]); ok && c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// positionKey returns the attributes that identify the position of an item in the view
+func (v *view) positionKey(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	key := map[string]*dynamodb.AttributeValue{}
+	for _, n := range v.keyNames() {
+		key[n] = copyAV(item[n])
+	}
+	return key
+}
+
+// partitionHash returns the hash that determines the order of partitions
+func partitionHash(av *dynamodb.AttributeValue) []byte {
+	sum := md5.Sum(keyBytes(av))
+	return sum[:]
+}
+
+// segmentOf returns the scan segment that an item belongs to
+func segmentOf(av *dynamodb.AttributeValue, total int64) int64 {
+	h := binary.BigEndian.Uint32(partitionHash(av)[:4])
+	return int64(uint64(h) * uint64(total) >> 32)
+}
+
+// keyOf returns a copy of the primary key attributes of an item
+func (tbl *table) keyOf(item map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	key := map[string]*dynamodb.AttributeValue{}
+	for _, n := range tbl.keys.names() {
+		key[n] = copyAV(item[n])
+	}
+	return key
+}
@@ -0,0 +1,303 @@
+package memddb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+var (
+	// maxTxItems is the maximum nr of operations in one transaction
+	maxTxItems = 100
+
+	// maxBatchGetKeys is the maximum nr of keys in one BatchGetItem request
+	maxBatchGetKeys = 100
+
+	// maxBatchWriteItems is the maximum nr of items in one BatchWriteItem request
+	maxBatchWriteItems = 25
+)
+
+// prepTxWrite validates one operation of a write transaction
+func (db *DB) prepTxWrite(wi *dynamodb.TransactWriteItem) (w *write, rv *string, err error) {
+	switch {
+	case wi.Put != nil:
+		w, err = db.prepPut(wi.Put.TableName, wi.Put.Item, wi.Put.ConditionExpression,
+			wi.Put.ExpressionAttributeNames, wi.Put.ExpressionAttributeValues)
+		return w, wi.Put.ReturnValuesOnConditionCheckFailure, err
+	case wi.Update != nil:
+		w, err = db.prepUpdate(wi.Update.TableName, wi.Update.Key, wi.Update.ConditionExpression,
+			wi.Update.UpdateExpression, wi.Update.ExpressionAttributeNames, wi.Update.ExpressionAttributeValues)
+		return w, wi.Update.ReturnValuesOnConditionCheckFailure, err
+	case wi.Delete != nil:
+		w, err = db.prepDelete(wi.Delete.TableName, wi.Delete.Key, wi.Delete.ConditionExpression,
+			wi.Delete.ExpressionAttributeNames, wi.Delete.ExpressionAttributeValues)
+		return w, wi.Delete.ReturnValuesOnConditionCheckFailure, err
+	case wi.ConditionCheck != nil:
+		w, err = db.prepCheck(wi.ConditionCheck.TableName, wi.ConditionCheck.Key, wi.ConditionCheck.ConditionExpression,
+			wi.ConditionCheck.ExpressionAttributeNames, wi.ConditionCheck.ExpressionAttributeValues)
+		return w, wi.ConditionCheck.ReturnValuesOnConditionCheckFailure, err
+	}
+
+	return nil, nil, validationErrorf("TransactItems can only contain one of Check, Put, Update or Delete")
+}
+
+// TransactWriteItemsWithContext applies all writes atomically, or none of them
+func (db *DB) TransactWriteItemsWithContext(
+	ctx aws.Context,
+	in *dynamodb.TransactWriteItemsInput,
+	opts ...request.Option,
+) (*dynamodb.TransactWriteItemsOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if len(in.TransactItems) < 1 || len(in.TransactItems) > maxTxItems {
+		return nil, validationErrorf("1 validation error detected: Value at 'transactItems' failed to satisfy constraint: Member must have length less than or equal to %d", maxTxItems)
+	}
+
+	var hash string
+	if in.ClientRequestToken != nil {
+		data, _ := json.Marshal(in.TransactItems)
+		sum := sha256.Sum256(data)
+		hash = hex.EncodeToString(sum[:])
+
+		if tok, ok := db.tokens[*in.ClientRequestToken]; ok && now().Before(tok.exp) {
+			if tok.hash != hash {
+				return nil, &dynamodb.IdempotentParameterMismatchException{
+					Message_: aws.String("The request uses the same client token as a previous, but non-identical request."),
+				}
+			}
+
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		}
+	}
+
+	writes := make([]*write, len(in.TransactItems))
+	rvs := make([]*string, len(in.TransactItems))
+	seen := map[*table]map[string]bool{}
+	for i, wi := range in.TransactItems {
+		var err error
+		if writes[i], rvs[i], err = db.prepTxWrite(wi); err != nil {
+			return nil, err
+		}
+
+		w := writes[i]
+		if seen[w.tbl] == nil {
+			seen[w.tbl] = map[string]bool{}
+		}
+
+		if seen[w.tbl][w.id] {
+			return nil, validationErrorf("Transaction request cannot include multiple operations on one item")
+		}
+
+		seen[w.tbl][w.id] = true
+	}
+
+	var failed bool
+	reasons := make([]*dynamodb.CancellationReason, len(writes))
+	for i, w := range writes {
+		reasons[i] = &dynamodb.CancellationReason{Code: aws.String("None")}
+
+		ok, err := w.ok()
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			failed = true
+			reasons[i].Code = aws.String("ConditionalCheckFailed")
+			reasons[i].Message = aws.String("The conditional request failed")
+			if aws.StringValue(rvs[i]) == dynamodb.ReturnValuesOnConditionCheckFailureAllOld {
+				reasons[i].Item = copyItem(w.old)
+			}
+		}
+	}
+
+	if failed {
+		return nil, txCanceled(reasons)
+	}
+
+	var cs capacities
+	for _, w := range writes {
+		cs.of(w.tbl, false).addWrite(w, 2)
+		w.commit()
+	}
+
+	if in.ClientRequestToken != nil {
+		db.tokens[*in.ClientRequestToken] = requestToken{hash: hash, exp: now().Add(idempotencyWindow)}
+	}
+
+	return &dynamodb.TransactWriteItemsOutput{ConsumedCapacity: cs.consumed(in.ReturnConsumedCapacity)}, nil
+}
+
+// txCanceled returns the error DynamoDB returns when a transaction is canceled
+func txCanceled(reasons []*dynamodb.CancellationReason) error {
+	var codes []string
+	for _, r := range reasons {
+		codes = append(codes, aws.StringValue(r.Code))
+	}
+
+	return &dynamodb.TransactionCanceledException{
+		Message_: aws.String("Transaction cancelled, please refer cancellation reasons for specific reasons [" +
+			strings.Join(codes, ", ") + "]"),
+		CancellationReasons: reasons,
+	}
+}
+
+// TransactGetItemsWithContext reads several items in one consistent snapshot
+func (db *DB) TransactGetItemsWithContext(
+	ctx aws.Context,
+	in *dynamodb.TransactGetItemsInput,
+	opts ...request.Option,
+) (*dynamodb.TransactGetItemsOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if len(in.TransactItems) < 1 || len(in.TransactItems) > maxTxItems {
+		return nil, validationErrorf("1 validation error detected: Value at 'transactItems' failed to satisfy constraint: Member must have length less than or equal to %d", maxTxItems)
+	}
+
+	var cs capacities
+	out := &dynamodb.TransactGetItemsOutput{}
+	for _, ti := range in.TransactItems {
+		if ti.Get == nil {
+			return nil, validationErrorf("1 validation error detected: Value null at 'transactItems.1.member.get' failed to satisfy constraint: Member must not be null")
+		}
+
+		it, tbl, size, err := db.get(ti.Get.TableName, ti.Get.Key, ti.Get.ProjectionExpression, ti.Get.ExpressionAttributeNames)
+		if err != nil {
+			return nil, err
+		}
+
+		cs.of(tbl, true).addRead(nil, size, true, 2)
+		out.Responses = append(out.Responses, &dynamodb.ItemResponse{Item: it})
+	}
+
+	out.ConsumedCapacity = cs.consumed(in.ReturnConsumedCapacity)
+	return out, nil
+}
+
+// BatchGetItemWithContext reads several items from one or more tables
+func (db *DB) BatchGetItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.BatchGetItemInput,
+	opts ...request.Option,
+) (*dynamodb.BatchGetItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var n int
+	for _, ka := range in.RequestItems {
+		n += len(ka.Keys)
+	}
+
+	if n < 1 || n > maxBatchGetKeys {
+		return nil, validationErrorf("Too many items requested for the BatchGetItem call")
+	}
+
+	out := &dynamodb.BatchGetItemOutput{
+		Responses:       map[string][]map[string]*dynamodb.AttributeValue{},
+		UnprocessedKeys: map[string]*dynamodb.KeysAndAttributes{},
+	}
+
+	var cs capacities
+	for name, ka := range in.RequestItems {
+		tbl, err := db.table(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+
+		seen := map[string]bool{}
+		for _, key := range ka.Keys {
+			id, err := tbl.primaryKey(key)
+			if err != nil {
+				return nil, err
+			}
+
+			if seen[id] {
+				return nil, validationErrorf("Provided list of item keys contains duplicates")
+			}
+
+			seen[id] = true
+		}
+
+		out.Responses[name] = []map[string]*dynamodb.AttributeValue{}
+		for _, key := range ka.Keys {
+			it, _, size, err := db.get(aws.String(name), key, ka.ProjectionExpression, ka.ExpressionAttributeNames)
+			if err != nil {
+				return nil, err
+			}
+
+			cs.of(tbl, true).addRead(nil, size, aws.BoolValue(ka.ConsistentRead), 1)
+
+			if it != nil {
+				out.Responses[name] = append(out.Responses[name], it)
+			}
+		}
+	}
+
+	out.ConsumedCapacity = cs.consumed(in.ReturnConsumedCapacity)
+	return out, nil
+}
+
+// BatchWriteItemWithContext puts or deletes several items in one or more tables. The
+// individual writes are not atomic.
+func (db *DB) BatchWriteItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.BatchWriteItemInput,
+	opts ...request.Option,
+) (*dynamodb.BatchWriteItemOutput, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var writes []*write
+	seen := map[*table]map[string]bool{}
+	for name, wrs := range in.RequestItems {
+		for _, wr := range wrs {
+			var w *write
+			var err error
+			switch {
+			case wr.PutRequest != nil:
+				w, err = db.prepPut(aws.String(name), wr.PutRequest.Item, nil, nil, nil)
+			case wr.DeleteRequest != nil:
+				w, err = db.prepDelete(aws.String(name), wr.DeleteRequest.Key, nil, nil, nil)
+			default:
+				err = validationErrorf("Supplied AttributeValue has more than one datatypes set, must contain exactly one of the supported datatypes")
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			if seen[w.tbl] == nil {
+				seen[w.tbl] = map[string]bool{}
+			}
+
+			if seen[w.tbl][w.id] {
+				return nil, validationErrorf("Provided list of item keys contains duplicates")
+			}
+
+			seen[w.tbl][w.id] = true
+			writes = append(writes, w)
+		}
+	}
+
+	if len(writes) < 1 || len(writes) > maxBatchWriteItems {
+		return nil, validationErrorf("1 validation error detected: Value at 'requestItems' failed to satisfy constraint: Member must have length less than or equal to %d", maxBatchWriteItems)
+	}
+
+	var cs capacities
+	for _, w := range writes {
+		cs.of(w.tbl, false).addWrite(w, 1)
+		w.commit()
+	}
+
+	return &dynamodb.BatchWriteItemOutput{
+		UnprocessedItems: map[string][]*dynamodb.WriteRequest{},
+		ConsumedCapacity: cs.consumed(in.ReturnConsumedCapacity),
+	}, nil
+}
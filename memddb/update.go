@@ -0,0 +1,266 @@
+package memddb
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// applyUpdate applies the update actions to a copy of 'old'. All operands are evaluated against
+// the old item, as DynamoDB does. It returns the new item and the names of the top-level
+// attributes that were updated.
+func applyUpdate(
+	old map[string]*dynamodb.AttributeValue,
+	u *update,
+	keys keySchema,
+) (item map[string]*dynamodb.AttributeValue, updated []string, err error) {
+	var paths []path
+	for _, a := range u.set {
+		paths = append(paths, a.p)
+	}
+	for _, a := range u.remove {
+		paths = append(paths, a.p)
+	}
+	for _, a := range u.add {
+		paths = append(paths, a.p)
+	}
+	for _, a := range u.delete {
+		paths = append(paths, a.p)
+	}
+
+	if err = checkPaths(paths, keys); err != nil {
+		return nil, nil, err
+	}
+
+	// evaluate all values before anything is changed
+	setVals := make([]*dynamodb.AttributeValue, len(u.set))
+	for i, a := range u.set {
+		if setVals[i], err = evalOperand(old, a.v); err != nil {
+			return nil, nil, err
+		}
+
+		if setVals[i] == nil {
+			return nil, nil, validationErrorf("The provided expression refers to an attribute that does not exist in the item")
+		}
+	}
+
+	addVals := make([]*dynamodb.AttributeValue, len(u.add))
+	for i, a := range u.add {
+		cur, _ := evalOperand(old, a.v)
+		if addVals[i], err = addValue(resolve(old, a.p), cur); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	delVals := make([]*dynamodb.AttributeValue, len(u.delete))
+	for i, a := range u.delete {
+		cur, _ := evalOperand(old, a.v)
+		if delVals[i], err = deleteValue(resolve(old, a.p), cur); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	item = copyItem(old)
+	for i, a := range u.set {
+		if err = setPath(item, a.p, copyAV(setVals[i])); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for i, a := range u.add {
+		if err = setPath(item, a.p, addVals[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for i, a := range u.delete {
+		if delVals[i] == nil {
+			removePath(item, a.p)
+			continue
+		}
+
+		if err = setPath(item, a.p, delVals[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// removals are applied last, in reverse order so list indexes refer to the list before the update
+	rms := append([]removeAction{}, u.remove...)
+	sort.SliceStable(rms, func(i, j int) bool { return pathLess(rms[j].p, rms[i].p) })
+	for _, a := range rms {
+		removePath(item, a.p)
+	}
+
+	seen := map[string]bool{}
+	for _, p := range paths {
+		if !seen[p[0].name] {
+			updated = append(updated, p[0].name)
+			seen[p[0].name] = true
+		}
+	}
+
+	return item, updated, nil
+}
+
+// checkPaths makes sure that no two update paths overlap and that no key is updated
+func checkPaths(paths []path, keys keySchema) error {
+	for _, p := range paths {
+		if p[0].name == keys.pk || (keys.sk != "" && p[0].name == keys.sk) {
+			return validationErrorf("One or more parameter values were invalid: Cannot update attribute %s. This attribute is part of the key", p[0].name)
+		}
+	}
+
+	if a, b, ok := overlapping(paths); ok {
+		return validationErrorf("Invalid UpdateExpression: Two document paths overlap with each other; must remove or rewrite one of these paths; path one: [%s], path two: [%s]", a, b)
+	}
+
+	return nil
+}
+
+// overlapping returns the first two paths of which one is equal to, or nested in, the other
+func overlapping(paths []path) (a, b string, ok bool) {
+	for i, p := range paths {
+		for _, q := range paths[i+1:] {
+			a, b = p.String(), q.String()
+			if a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".") ||
+				strings.HasPrefix(a, b+"[") || strings.HasPrefix(b, a+"[") {
+				return a, b, true
+			}
+		}
+	}
+
+	return "", "", false
+}
+
+// addValue returns the result of the ADD action with value 'v' on the current value 'cur'
+func addValue(cur, v *dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+	switch typeOf(v) {
+	case typeN:
+		if cur == nil {
+			return copyAV(v), nil
+		}
+
+		if typeOf(cur) != typeN {
+			return nil, validationErrorf("An operand in the update expression has an incorrect data type")
+		}
+
+		a, err := parseNum(*cur.N)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := parseNum(*v.N)
+		if err != nil {
+			return nil, err
+		}
+
+		return &dynamodb.AttributeValue{N: aws.String(formatNum(a.Add(a, b)))}, nil
+	case typeSS, typeNS, typeBS:
+		if cur == nil {
+			return copyAV(v), nil
+		}
+
+		if typeOf(cur) != typeOf(v) {
+			return nil, validationErrorf("An operand in the update expression has an incorrect data type")
+		}
+
+		keys := setKeys(cur)
+		for k := range setKeys(v) {
+			keys[k] = struct{}{}
+		}
+
+		return setFromKeys(typeOf(v), keys), nil
+	}
+
+	return nil, validationErrorf("Invalid UpdateExpression: Incorrect operand type for operator or function; operator: ADD, operand type: %s", typeOf(v))
+}
+
+// deleteValue returns the result of the DELETE action, nil means the attribute is removed
+func deleteValue(cur, v *dynamodb.AttributeValue) (*dynamodb.AttributeValue, error) {
+	switch typeOf(v) {
+	case typeSS, typeNS, typeBS:
+	default:
+		return nil, validationErrorf("Invalid UpdateExpression: Incorrect operand type for operator or function; operator: DELETE, operand type: %s", typeOf(v))
+	}
+
+	if cur == nil {
+		return nil, nil
+	}
+
+	if typeOf(cur) != typeOf(v) {
+		return nil, validationErrorf("An operand in the update expression has an incorrect data type")
+	}
+
+	keys := setKeys(cur)
+	for k := range setKeys(v) {
+		delete(keys, k)
+	}
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	return setFromKeys(typeOf(v), keys), nil
+}
+
+// setPath sets the value at path 'p', the parent of the path must exist
+func setPath(item map[string]*dynamodb.AttributeValue, p path, v *dynamodb.AttributeValue) error {
+	if len(p) == 1 {
+		item[p[0].name] = v
+		return nil
+	}
+
+	parent := resolve(item, p[:len(p)-1])
+	last := p[len(p)-1]
+	switch {
+	case last.isIdx && typeOf(parent) == typeL:
+		if last.index >= len(parent.L) {
+			parent.L = append(parent.L, v)
+		} else {
+			parent.L[last.index] = v
+		}
+	case !last.isIdx && typeOf(parent) == typeM:
+		parent.M[last.name] = v
+	default:
+		return validationErrorf("The document path provided in the update expression is invalid for update")
+	}
+
+	return nil
+}
+
+// removePath removes the value at path 'p', if it exists
+func removePath(item map[string]*dynamodb.AttributeValue, p path) {
+	if len(p) == 1 {
+		delete(item, p[0].name)
+		return
+	}
+
+	parent := resolve(item, p[:len(p)-1])
+	last := p[len(p)-1]
+	switch {
+	case last.isIdx && typeOf(parent) == typeL:
+		if last.index < len(parent.L) {
+			parent.L = append(parent.L[:last.index], parent.L[last.index+1:]...)
+		}
+	case !last.isIdx && typeOf(parent) == typeM:
+		delete(parent.M, last.name)
+	}
+}
+
+// pathLess orders paths element by element, list indexes are compared numerically
+func pathLess(a, b path) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		switch {
+		case a[i].isIdx && b[i].isIdx && a[i].index != b[i].index:
+			return a[i].index < b[i].index
+		case !a[i].isIdx && !b[i].isIdx && a[i].name != b[i].name:
+			return a[i].name < b[i].name
+		case a[i].isIdx != b[i].isIdx:
+			return b[i].isIdx
+		}
+	}
+
+	return len(a) < len(b)
+}
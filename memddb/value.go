@@ -0,0 +1,366 @@
+package memddb
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// attribute type descriptors as used by DynamoDB
+const (
+	typeS    = "S"
+	typeN    = "N"
+	typeB    = "B"
+	typeBOOL = "BOOL"
+	typeNULL = "NULL"
+	typeSS   = "SS"
+	typeNS   = "NS"
+	typeBS   = "BS"
+	typeL    = "L"
+	typeM    = "M"
+)
+
+// typeOf returns the type descriptor of an attribute value
+func typeOf(av *dynamodb.AttributeValue) string {
+	switch {
+	case av == nil:
+		return ""
+	case av.S != nil:
+		return typeS
+	case av.N != nil:
+		return typeN
+	case av.B != nil:
+		return typeB
+	case av.BOOL != nil:
+		return typeBOOL
+	case av.NULL != nil:
+		return typeNULL
+	case av.SS != nil:
+		return typeSS
+	case av.NS != nil:
+		return typeNS
+	case av.BS != nil:
+		return typeBS
+	case av.L != nil:
+		return typeL
+	case av.M != nil:
+		return typeM
+	}
+
+	return ""
+}
+
+// copyItem returns a deep copy of an item
+func copyItem(m map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	if m == nil {
+		return nil
+	}
+
+	n := make(map[string]*dynamodb.AttributeValue, len(m))
+	for k, v := range m {
+		n[k] = copyAV(v)
+	}
+	return n
+}
+
+// copyAV returns a deep copy of an attribute value. Numbers are normalized as DynamoDB
+// doesn't preserve their representation either.
+func copyAV(av *dynamodb.AttributeValue) *dynamodb.AttributeValue {
+	if av == nil {
+		return nil
+	}
+
+	c := &dynamodb.AttributeValue{}
+	switch typeOf(av) {
+	case typeS:
+		c.S = aws.String(*av.S)
+	case typeN:
+		c.N = aws.String(normalizeNum(*av.N))
+	case typeB:
+		c.B = append([]byte{}, av.B...)
+	case typeBOOL:
+		c.BOOL = aws.Bool(*av.BOOL)
+	case typeNULL:
+		c.NULL = aws.Bool(*av.NULL)
+	case typeSS:
+		for _, s := range av.SS {
+			c.SS = append(c.SS, aws.String(aws.StringValue(s)))
+		}
+	case typeNS:
+		for _, s := range av.NS {
+			c.NS = append(c.NS, aws.String(normalizeNum(aws.StringValue(s))))
+		}
+	case typeBS:
+		for _, b := range av.BS {
+			c.BS = append(c.BS, append([]byte{}, b...))
+		}
+	case typeL:
+		c.L = []*dynamodb.AttributeValue{}
+		for _, v := range av.L {
+			c.L = append(c.L, copyAV(v))
+		}
+	case typeM:
+		c.M = copyItem(av.M)
+	}
+
+	return c
+}
+
+// parseNum parses a DynamoDB number
+func parseNum(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return nil, validationErrorf("A value provided cannot be converted into a number")
+	}
+	return r, nil
+}
+
+// formatNum formats a number the way DynamoDB returns it
+func formatNum(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+
+	s := r.FloatString(38)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// normalizeNum returns the canonical representation of a number string
+func normalizeNum(s string) string {
+	r, err := parseNum(s)
+	if err != nil {
+		return s
+	}
+	return formatNum(r)
+}
+
+// compareAV compares two scalar values of the same type. It returns false if the values
+// cannot be compared.
+func compareAV(a, b *dynamodb.AttributeValue) (int, bool) {
+	ta, tb := typeOf(a), typeOf(b)
+	if ta != tb {
+		return 0, false
+	}
+
+	switch ta {
+	case typeS:
+		return strings.Compare(*a.S, *b.S), true
+	case typeB:
+		return bytes.Compare(a.B, b.B), true
+	case typeN:
+		ra, err := parseNum(*a.N)
+		if err != nil {
+			return 0, false
+		}
+
+		rb, err := parseNum(*b.N)
+		if err != nil {
+			return 0, false
+		}
+
+		return ra.Cmp(rb), true
+	}
+
+	return 0, false
+}
+
+// equalAV returns whether two attribute values are equal
+func equalAV(a, b *dynamodb.AttributeValue) bool {
+	ta, tb := typeOf(a), typeOf(b)
+	if ta != tb || ta == "" {
+		return false
+	}
+
+	switch ta {
+	case typeS, typeB, typeN:
+		c, ok := compareAV(a, b)
+		return ok && c == 0
+	case typeBOOL:
+		return *a.BOOL == *b.BOOL
+	case typeNULL:
+		return true
+	case typeSS, typeNS, typeBS:
+		sa, sb := setKeys(a), setKeys(b)
+		if len(sa) != len(sb) {
+			return false
+		}
+
+		for k := range sa {
+			if _, ok := sb[k]; !ok {
+				return false
+			}
+		}
+		return true
+	case typeL:
+		if len(a.L) != len(b.L) {
+			return false
+		}
+
+		for i := range a.L {
+			if !equalAV(a.L[i], b.L[i]) {
+				return false
+			}
+		}
+		return true
+	case typeM:
+		if len(a.M) != len(b.M) {
+			return false
+		}
+
+		for k, v := range a.M {
+			if !equalAV(v, b.M[k]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// setKeys returns the elements of a set as (normalized) strings
+func setKeys(av *dynamodb.AttributeValue) map[string]struct{} {
+	keys := map[string]struct{}{}
+	switch typeOf(av) {
+	case typeSS:
+		for _, s := range av.SS {
+			keys[aws.StringValue(s)] = struct{}{}
+		}
+	case typeNS:
+		for _, s := range av.NS {
+			keys[normalizeNum(aws.StringValue(s))] = struct{}{}
+		}
+	case typeBS:
+		for _, b := range av.BS {
+			keys[string(b)] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// setFromKeys builds a set of type 'typ' from (normalized) string elements
+func setFromKeys(typ string, keys map[string]struct{}) *dynamodb.AttributeValue {
+	elems := make([]string, 0, len(keys))
+	for k := range keys {
+		elems = append(elems, k)
+	}
+
+	sort.Strings(elems)
+	av := &dynamodb.AttributeValue{}
+	for _, e := range elems {
+		switch typ {
+		case typeSS:
+			av.SS = append(av.SS, aws.String(e))
+		case typeNS:
+			av.NS = append(av.NS, aws.String(e))
+		case typeBS:
+			av.BS = append(av.BS, []byte(e))
+		}
+	}
+	return av
+}
+
+// keyBytes returns the raw bytes of a key attribute value, used for hashing and ordering
+func keyBytes(av *dynamodb.AttributeValue) []byte {
+	switch typeOf(av) {
+	case typeS:
+		return []byte(*av.S)
+	case typeN:
+		return []byte(normalizeNum(*av.N))
+	case typeB:
+		return av.B
+	}
+	return nil
+}
+
+// itemSize returns the size of an item as DynamoDB calculates it
+func itemSize(item map[string]*dynamodb.AttributeValue) (n int) {
+	for name, av := range item {
+		n += len(name) + avSize(av)
+	}
+	return
+}
+
+// avSize returns the size of an attribute value
+func avSize(av *dynamodb.AttributeValue) (n int) {
+	switch typeOf(av) {
+	case typeS:
+		return len(*av.S)
+	case typeN:
+		return len(normalizeNum(*av.N))/2 + 1
+	case typeB:
+		return len(av.B)
+	case typeBOOL, typeNULL:
+		return 1
+	case typeSS, typeNS, typeBS:
+		for k := range setKeys(av) {
+			n += len(k)
+		}
+		return
+	case typeL:
+		n = 3
+		for _, v := range av.L {
+			n += 1 + avSize(v)
+		}
+		return
+	case typeM:
+		return 3 + len(av.M) + itemSize(av.M)
+	}
+	return 0
+}
+
+// validateAV checks an attribute value that is provided by the user
+func validateAV(av *dynamodb.AttributeValue) error {
+	if av == nil {
+		return validationErrorf("Supplied AttributeValue is empty, must contain exactly one of the supported datatypes")
+	}
+
+	var n int
+	for _, set := range []bool{
+		av.S != nil, av.N != nil, av.B != nil, av.BOOL != nil, av.NULL != nil,
+		av.SS != nil, av.NS != nil, av.BS != nil, av.L != nil, av.M != nil,
+	} {
+		if set {
+			n++
+		}
+	}
+
+	if n != 1 {
+		return validationErrorf("Supplied AttributeValue has more than one datatypes set, must contain exactly one of the supported datatypes")
+	}
+
+	switch typeOf(av) {
+	case typeN:
+		if _, err := parseNum(*av.N); err != nil {
+			return err
+		}
+	case typeSS, typeNS, typeBS:
+		keys := setKeys(av)
+		if len(keys) == 0 {
+			return validationErrorf("One or more parameter values were invalid: An string set  may not be empty")
+		}
+
+		if len(keys) != len(av.SS)+len(av.NS)+len(av.BS) {
+			return validationErrorf("One or more parameter values were invalid: Input collection contains duplicates")
+		}
+	case typeL:
+		for _, v := range av.L {
+			if err := validateAV(v); err != nil {
+				return err
+			}
+		}
+	case typeM:
+		for _, v := range av.M {
+			if err := validateAV(v); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
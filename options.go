@@ -1,13 +1,31 @@
 package ddb
 
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
 // DefaultOptions will be used when no options are specified
 var DefaultOptions = []Option{EnableEmptyCollections()}
 
 // Options holds option values for all options that we support
 type Options struct {
-	enableEmptyCollections bool
+	enableEmptyCollections  bool
+	enableBatchReads        bool
+	enableBatchWrites       bool
+	enableNonAtomicChunking bool
+	enableIdempotencyTokens bool
+	enableConsumedCapacity  bool
+	enableConsistentRead    bool
+	cursorSecret            []byte
+	encryptCursors          bool
+	outboxTable             string
+	indexes                 map[indexKey]Index
 }
 
+// indexKey identifies an index of a table
+type indexKey struct{ table, index string }
+
 // Apply options
 func (opts *Options) Apply(os ...Option) {
 	for _, o := range os {
@@ -15,6 +33,15 @@ func (opts *Options) Apply(os ...Option) {
 	}
 }
 
+// returnConsumedCapacity returns the ReturnConsumedCapacity parameter for inputs
+func (opts Options) returnConsumedCapacity() *string {
+	if !opts.enableConsumedCapacity {
+		return nil
+	}
+
+	return aws.String(dynamodb.ReturnConsumedCapacityIndexes)
+}
+
 // Option get's called to configure options
 type Option func(*Options)
 
@@ -22,3 +49,83 @@ type Option func(*Options)
 func EnableEmptyCollections() func(o *Options) {
 	return func(o *Options) { o.enableEmptyCollections = true }
 }
+
+// EnableBatchReads is an option that makes readers with more then one get operation use the
+// (non-transactional) BatchGetItem api instead of TransactGetItems.
+func EnableBatchReads() func(o *Options) {
+	return func(o *Options) { o.enableBatchReads = true }
+}
+
+// EnableBatchWrites is an option that makes writers with more then one put or delete operation
+// use the (non-transactional) BatchWriteItem api instead of TransactWriteItems. Writers with this
+// option will refuse conditions, update and check operations.
+func EnableBatchWrites() func(o *Options) {
+	return func(o *Options) { o.enableBatchWrites = true }
+}
+
+// EnableNonAtomicChunking is an option that allows writers to split operations that do not fit
+// into a single transaction into several transactions that are run in order. The write as a whole
+// is then no longer atomic: when a chunk fails the chunks before it remain committed and a
+// TxChunkError is returned that reports this.
+func EnableNonAtomicChunking() func(o *Options) {
+	return func(o *Options) { o.enableNonAtomicChunking = true }
+}
+
+// EnableIdempotencyTokens is an option that makes writers derive a ClientRequestToken from a hash
+// of the items in each transaction. Running the same logical write again within the idempotency
+// window of DynamoDB (10 minutes) is then safe. Writes that are downgraded to a single (non
+// transactional) operation are not affected, set an explicit token on the Writer for that.
+func EnableIdempotencyTokens() func(o *Options) {
+	return func(o *Options) { o.enableIdempotencyTokens = true }
+}
+
+// EnableConsumedCapacity is an option that asks DynamoDB to return the capacity that is consumed,
+// for the table and each index, by every operation. The capacity is added to the accumulator of
+// the context (see WithCapacity).
+func EnableConsumedCapacity() func(o *Options) {
+	return func(o *Options) { o.enableConsumedCapacity = true }
+}
+
+// ConsistentRead is an option that makes gets, queries and scans use strongly consistent reads.
+// It can be overwritten per context with WithConsistentRead. DynamoDB doesn't support consistent
+// reads on global secondary indexes, so it only applies to queries and scans of indexes that were
+// declared local with the Schemas option. Other indexes are read as their input asks.
+func ConsistentRead() func(o *Options) {
+	return func(o *Options) { o.enableConsistentRead = true }
+}
+
+// CursorSecret is an option that sets the secret that the cursors of query and scan results are
+// signed with (see PagedResult). Cursors can only be resumed from with the same secret.
+func CursorSecret(secret []byte) func(o *Options) {
+	return func(o *Options) { o.cursorSecret = secret }
+}
+
+// EncryptCursors is an option that makes cursors encrypted, instead of just signed, such that
+// clients can't read the key attributes they hold.
+func EncryptCursors() func(o *Options) {
+	return func(o *Options) { o.encryptCursors = true }
+}
+
+// Schemas is an option that declares the schemas of the tables that are read. The ConsistentRead
+// option then applies to queries and scans of their local indexes, and inputs that ask for a
+// consistent read of one of their global indexes are refused with an
+// ErrConsistentReadOnGlobalIndex before anything is send to DynamoDB.
+func Schemas(schemas ...*Schema) func(o *Options) {
+	return func(o *Options) {
+		if o.indexes == nil {
+			o.indexes = map[indexKey]Index{}
+		}
+
+		for _, s := range schemas {
+			for _, idx := range s.Indexes {
+				o.indexes[indexKey{s.Table, idx.Name}] = idx
+			}
+		}
+	}
+}
+
+// Outbox is an option that sets the table that events are written to by the Emit method of
+// writers. The table must have the schema of OutboxItem.
+func Outbox(table string) func(o *Options) {
+	return func(o *Options) { o.outboxTable = table }
+}
@@ -0,0 +1,188 @@
+package ddb
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// OutboxIndex is the name of the global index of the outbox table that holds the pending events,
+// sorted by when they were emitted. All pending events share a single partition of the index, so
+// the rate at which events can be emitted is limited to what one partition sustains (about 1000
+// writes per second).
+const OutboxIndex = "outbox-pending"
+
+// OutboxPending is the value of the Pending attribute of events that haven't been dispatched
+const OutboxPending = "pending"
+
+// OutboxItem is how an emitted event is stored in the outbox table. Emitted sorts by the clock of
+// the host that emitted the event, see Emit. Pending is removed once the event has been
+// dispatched, such that the index only holds the pending events.
+type OutboxItem struct {
+	ID         string                              `dynamodbav:"pk"`
+	Pending    string                              `dynamodbav:"pending,omitempty"`
+	Emitted    string                              `dynamodbav:"emitted"`
+	Type       string                              `dynamodbav:"type"`
+	Event      map[string]*dynamodb.AttributeValue `dynamodbav:"event"`
+	Dispatched int64                               `dynamodbav:"dispatched,omitempty"`
+}
+
+func (OutboxItem) Keys() (pk, sk string) { return "pk", "" }
+
+// Item allows the outbox item to be written as is
+func (it *OutboxItem) Item() Item { return it }
+
+// Indexes declares the index of the pending events
+func (OutboxItem) Indexes() []Index {
+	return []Index{{Name: OutboxIndex, PK: "pending", SK: "emitted"}}
+}
+
+// Emit adds a put of the event to the outbox table, configured with the Outbox option, to the
+// write. The event is thereby only recorded if the rest of the write succeeds. The event's item
+// is stored as an attribute of an OutboxItem, so it doesn't need keys that fit the outbox table.
+//
+// Events are stored with the time they were emitted, so they sort in emitted order as far as the
+// clocks of the emitting hosts agree. The put of an event fails if its ID exists, in which case
+// the write is treated as done. If the write has a ClientRequestToken, or the
+// EnableIdempotencyTokens option is set, the ID is derived from the content of the write. Running
+// the same logical write again then succeeds without changing anything, also after DynamoDB's
+// idempotency window. Building the write again within that window while setting the same
+// explicit token fails with an IdempotencyMismatchError, because the emitted time differs.
+func (tx *Writer) Emit(event Itemizer) *Writer {
+	if tx.err != nil {
+		return tx
+	}
+
+	switch {
+	case tx.opts.outboxTable == "":
+		tx.err = fmt.Errorf("no outbox table, configure it with the Outbox option")
+		return tx
+	case tx.opts.enableBatchWrites:
+		tx.err = fmt.Errorf("events are not supported in batch writes")
+		return tx
+	case tx.opts.enableNonAtomicChunking:
+		tx.err = fmt.Errorf("events are not supported in non-atomic chunked writes")
+		return tx
+	case event == nil || event.Item() == nil:
+		tx.err = fmt.Errorf("event has no item")
+		return tx
+	}
+
+	ev, err := MarshalMap(event.Item(), tx.opts.enableEmptyCollections)
+	if err != nil {
+		tx.err = fmt.Errorf("failed to marshal event: %w", err)
+		return tx
+	}
+
+	pk, _ := OutboxItem{}.Keys()
+	op := len(tx.writes)
+
+	var put dynamodb.Put
+	put.SetTableName(tx.opts.outboxTable)
+	tx.events = append(tx.events, op)
+	return tx.Put(expression.NewBuilder().WithCondition(expression.AttributeNotExists(expression.Name(pk))),
+		put, &OutboxItem{
+			ID:      outboxID(op),
+			Pending: OutboxPending,
+			Emitted: fmt.Sprintf("%019d.%03d", time.Now().UnixNano(), op),
+			Type:    reflect.Indirect(reflect.ValueOf(event)).Type().Name(),
+			Event:   ev,
+		})
+}
+
+// outboxID returns a unique id that sorts by the time it was created and then by the index of
+// the operation in the write
+func outboxID(op int) string {
+	var b [4]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic("ddb: failed to generate outbox id: " + err.Error())
+	}
+
+	return fmt.Sprintf("%019d.%03d.%s", time.Now().UnixNano(), op, hex.EncodeToString(b[:]))
+}
+
+// deriveEventIDs replaces the ids of the emitted events with ids that are derived from the
+// content of the write and its token, if the write is idempotent. A retry of the same logical
+// write then puts the same events, which fails because they exist.
+func (tx *Writer) deriveEventIDs() error {
+	if len(tx.events) == 0 || (tx.token == nil && !tx.opts.enableIdempotencyTokens) {
+		return nil
+	}
+
+	pk, _ := OutboxItem{}.Keys()
+	setID := func(i int, id string) {
+		tx.writes[i].Put.Item[pk] = &dynamodb.AttributeValue{S: aws.String(id)}
+		tx.keys[i][pk] = tx.writes[i].Put.Item[pk]
+	}
+
+	// the ids, and the emitted times that differ between attempts, are left out of the hash such
+	// that running the writer again, or building it again, derives the same ids
+	emitted := make([]*dynamodb.AttributeValue, len(tx.events))
+	for j, i := range tx.events {
+		emitted[j] = tx.writes[i].Put.Item["emitted"]
+		delete(tx.writes[i].Put.Item, "emitted")
+		setID(i, "")
+	}
+
+	data, err := json.Marshal(tx.writes)
+	for j, i := range tx.events {
+		tx.writes[i].Put.Item["emitted"] = emitted[j]
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to marshal write for event ids: %w", err)
+	}
+
+	sum := hashToken(append(data, aws.StringValue(tx.token)...))
+	for _, i := range tx.events {
+		setID(i, fmt.Sprintf("%s.%03d", sum, i))
+	}
+
+	return nil
+}
+
+// alreadyEmitted returns whether the write failed only because its events exist, which means the
+// same logical write succeeded before
+func (tx *Writer) alreadyEmitted(err error) bool {
+	if len(tx.events) == 0 {
+		return false
+	}
+
+	var tcerr *TxCanceledError
+	if !errors.As(err, &tcerr) {
+		var cfe *ConditionFailedError
+		return len(tx.writes) == 1 && errors.As(err, &cfe)
+	}
+
+	var emitted bool
+	for _, r := range tcerr.Reasons {
+		switch {
+		case r.Code == TxReasonNone:
+		case r.Code == TxReasonConditionalCheckFailed && tx.isEvent(r.Op):
+			emitted = true
+		default:
+			return false
+		}
+	}
+
+	return emitted
+}
+
+// isEvent returns whether the operation at index 'op' puts an emitted event
+func (tx *Writer) isEvent(op int) bool {
+	for _, i := range tx.events {
+		if i == op {
+			return true
+		}
+	}
+
+	return false
+}
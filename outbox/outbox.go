@@ -0,0 +1,181 @@
+// Package outbox relays the events that writers emitted to the outbox table to a publisher
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/gohandle/ddb"
+)
+
+// CreateTableInput returns the input that creates an outbox table
+func CreateTableInput(table string) *dynamodb.CreateTableInput {
+	s, err := ddb.NewSchema(table, ddb.OutboxItem{})
+	if err != nil {
+		panic("outbox: invalid outbox schema: " + err.Error())
+	}
+
+	return s.CreateTableInput()
+}
+
+// Message is an event that is pending in the outbox
+type Message struct {
+	ID   string
+	Type string
+	Item map[string]*dynamodb.AttributeValue
+}
+
+// Scan unmarshals the item of the event into an entity
+func (m Message) Scan(v interface {
+	ddb.Itemizer
+	ddb.Deitemizer
+}) (err error) {
+	it := v.Item()
+	if err = dynamodbattribute.UnmarshalMap(m.Item, it); err != nil {
+		return
+	}
+
+	return v.FromItem(it)
+}
+
+// entry is the entity that maps a message onto the outbox item
+type entry struct{ msg Message }
+
+func (en entry) Item() ddb.Item {
+	return &ddb.OutboxItem{ID: en.msg.ID, Type: en.msg.Type, Event: en.msg.Item}
+}
+
+func (en *entry) FromItem(it ddb.Item) error {
+	oi := it.(*ddb.OutboxItem)
+	en.msg = Message{ID: oi.ID, Type: oi.Type, Item: oi.Event}
+	return nil
+}
+
+// PublishError is returned when the publisher failed to publish an event
+type PublishError struct {
+	ID  string
+	Err error
+}
+
+func (e *PublishError) Error() string {
+	return fmt.Sprintf("failed to publish event '%s': %v", e.ID, e.Err)
+}
+
+func (e *PublishError) Unwrap() error { return e.Err }
+
+// Publisher publishes a message. Messages are delivered at least once, a message is published
+// again when marking it as dispatched failed.
+type Publisher func(ctx context.Context, msg Message) error
+
+// Options configure a relay
+type Options struct {
+	logs *slog.Logger
+}
+
+// Option configures the relay
+type Option func(*Options)
+
+// Logger is an option that sets the logger that Run reports failed dispatches to. Defaults to
+// slog.Default().
+func Logger(logs *slog.Logger) func(o *Options) {
+	return func(o *Options) { o.logs = logs }
+}
+
+// Relay publishes the pending events of an outbox table in the order they were emitted
+type Relay struct {
+	ddb     ddb.Dynamo
+	table   string
+	publish Publisher
+	opts    Options
+}
+
+// NewRelay inits a relay for the outbox 'table'
+func NewRelay(d ddb.Dynamo, table string, publish Publisher, opts ...Option) *Relay {
+	r := &Relay{ddb: d, table: table, publish: publish, opts: Options{logs: slog.Default()}}
+	for _, o := range opts {
+		o(&r.opts)
+	}
+
+	return r
+}
+
+// Dispatch publishes the pending events in the order they were emitted and marks each as
+// dispatched. It stops at the first event that fails to publish, such that later events are not
+// published before it, and returns a PublishError. It returns the nr of events that were
+// dispatched.
+//
+// The order is best effort: emitted times come from the clocks of the emitting hosts (see
+// ddb.Writer.Emit) and the index of pending events is eventually consistent, so an event that was
+// emitted just before a later one may only show up in a next Dispatch. The index keeps all
+// pending events in one partition, which limits the throughput of the outbox.
+func (r *Relay) Dispatch(ctx context.Context) (n int, err error) {
+	var in dynamodb.QueryInput
+	in.SetTableName(r.table)
+	in.SetIndexName(ddb.OutboxIndex)
+
+	res, err := ddb.Query(e.NewBuilder().WithKeyCondition(
+		e.Key("pending").Equal(e.Value(ddb.OutboxPending))), in).Run(ctx, r.ddb)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query pending events: %w", err)
+	}
+
+	for res.Next() {
+		var en entry
+		if err = res.Scan(&en); err != nil {
+			return n, fmt.Errorf("failed to scan event: %w", err)
+		}
+
+		if err = r.publish(ctx, en.msg); err != nil {
+			return n, &PublishError{ID: en.msg.ID, Err: err}
+		}
+
+		var upd dynamodb.Update
+		upd.SetTableName(r.table)
+		_, err = ddb.Update(e.NewBuilder().
+			WithCondition(e.AttributeExists(e.Name("pending"))).
+			WithUpdate(e.
+				Remove(e.Name("pending")).
+				Set(e.Name("dispatched"), e.Value(time.Now().UnixMilli()))),
+			upd, &en).
+			Run(ctx, r.ddb)
+
+		// another relay may have dispatched the event concurrently
+		var cfe *ddb.ConditionFailedError
+		switch {
+		case errors.As(err, &cfe):
+			continue
+		case err != nil:
+			return n, fmt.Errorf("failed to mark event '%s' as dispatched: %w", en.msg.ID, err)
+		}
+
+		n++
+	}
+
+	return n, res.Err()
+}
+
+// Run dispatches the pending events at every interval until the context is done, it then
+// returns the context's error. A failed dispatch, such as an event that failed to publish or a
+// throttled read, is logged and retried at the next interval.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if _, err := r.Dispatch(ctx); err != nil && ctx.Err() == nil {
+			r.opts.logs.LogAttrs(ctx, slog.LevelWarn, "outbox: failed to dispatch events",
+				slog.String("table", r.table), slog.String("error", err.Error()))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
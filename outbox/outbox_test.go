@@ -0,0 +1,152 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/gohandle/ddb"
+	"github.com/gohandle/ddb/internal/ddbtest"
+)
+
+type orderItem struct {
+	PK    string `dynamodbav:"pk"`
+	Total int    `dynamodbav:"total"`
+}
+
+func (orderItem) Keys() (pk, sk string) { return "pk", "" }
+
+type order struct {
+	ID    string
+	Total int
+}
+
+func (o order) Item() ddb.Item { return &orderItem{PK: "order#" + o.ID, Total: o.Total} }
+
+func (o *order) FromItem(it ddb.Item) error {
+	o.ID, o.Total = it.(*orderItem).PK[len("order#"):], it.(*orderItem).Total
+	return nil
+}
+
+type orderPlaced struct{ order }
+
+// throttledDynamo fails the first 'n' queries
+type throttledDynamo struct {
+	ddb.Dynamo
+	n     int
+	calls int
+}
+
+func (tddb *throttledDynamo) QueryWithContext(
+	ctx aws.Context,
+	in *dynamodb.QueryInput,
+	opts ...request.Option,
+) (*dynamodb.QueryOutput, error) {
+	tddb.calls++
+	if tddb.calls <= tddb.n {
+		return nil, &dynamodb.ProvisionedThroughputExceededException{}
+	}
+
+	return tddb.Dynamo.QueryWithContext(ctx, in, opts...)
+}
+
+func TestRelay(t *testing.T) {
+	ctx, tbl, otbl := context.Background(), t.Name(), t.Name()+"Outbox"
+
+	s, _ := ddb.NewSchema(tbl, orderItem{})
+	mddb := ddbtest.MemDB(t, s.CreateTableInput(), CreateTableInput(otbl))
+
+	// idempotent writes derive the event ids from their content, the events still come out in
+	// the order they were emitted
+	place := func(t *testing.T, o *order) {
+		var put dynamodb.Put
+		put.SetTableName(tbl)
+		if _, err := ddb.NewWriter(ddb.EnableIdempotencyTokens(), ddb.Outbox(otbl)).
+			Put(e.Builder{}, put, o).
+			Emit(&orderPlaced{*o}).
+			Run(ctx, mddb); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+	}
+
+	for i, id := range []string{"c", "a", "b"} {
+		place(t, &order{ID: id, Total: i})
+	}
+
+	var published []order
+	fail := errors.New("broker down")
+	publish := func(ctx context.Context, msg Message) error {
+		var o order
+		if err := msg.Scan(&o); err != nil {
+			return err
+		}
+
+		if msg.Type != "orderPlaced" {
+			t.Fatalf("got: %v", msg.Type)
+		}
+
+		if o.ID == "a" && fail != nil {
+			return fail
+		}
+
+		published = append(published, o)
+		return nil
+	}
+
+	relay := NewRelay(mddb, otbl, publish)
+	n, err := relay.Dispatch(ctx)
+
+	var perr *PublishError
+	if n != 1 || !errors.As(err, &perr) || !errors.Is(err, fail) {
+		t.Fatalf("got: %v %v", n, err)
+	}
+
+	fail = nil
+	if n, err = relay.Dispatch(ctx); n != 2 || err != nil {
+		t.Fatalf("got: %v %v", n, err)
+	}
+
+	if len(published) != 3 || published[0].ID != "c" || published[1].ID != "a" ||
+		published[2].ID != "b" || published[2].Total != 2 {
+		t.Fatalf("got: %v", published)
+	}
+
+	if n, err = relay.Dispatch(ctx); n != 0 || err != nil {
+		t.Fatalf("got: %v %v", n, err)
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		// the same write after its event was dispatched doesn't make it pending again
+		place(t, &order{ID: "a", Total: 1})
+		if n, err := relay.Dispatch(ctx); n != 0 || err != nil {
+			t.Fatalf("got: %v %v", n, err)
+		}
+	})
+
+	t.Run("run", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		place(t, &order{ID: "d", Total: 3})
+
+		var logs bytes.Buffer
+		relay := NewRelay(&throttledDynamo{Dynamo: mddb, n: 2}, otbl, publish,
+			Logger(slog.New(slog.NewTextHandler(&logs, nil))))
+		if err := relay.Run(ctx, 5*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got: %v", err)
+		}
+
+		if strings.Count(logs.String(), "failed to dispatch") != 2 ||
+			len(published) != 4 || published[3].ID != "d" {
+			t.Fatalf("got: %v %s", published, logs.String())
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/gohandle/ddb/internal/ddbtest"
+)
+
+func TestEmit(t *testing.T) {
+	ctx, tbl, otbl := context.Background(), table1(t.Name()), t.Name()+"Outbox"
+	mddb := ddbtest.MemDB(t, tbl.createInput(), mustSchema(otbl, OutboxItem{}).CreateTableInput())
+
+	pending := func(t *testing.T) (its []*OutboxItem) {
+		var in dynamodb.QueryInput
+		in.SetTableName(otbl)
+		in.SetIndexName(OutboxIndex)
+		r, err := Query(e.NewBuilder().WithKeyCondition(
+			e.Key("pending").Equal(e.Value(OutboxPending))), in).Run(ctx, mddb)
+		if err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		for r.Next() {
+			var it OutboxItem
+			if err = r.Scan(&outboxEntity{&it}); err != nil {
+				t.Fatalf("got: %v", err)
+			}
+			its = append(its, &it)
+		}
+		return
+	}
+
+	if _, err := NewWriter().Emit(&table1Entity{ID: 1}).Run(ctx, mddb); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if _, err := NewWriter(EnableBatchWrites(), Outbox(otbl)).Emit(&table1Entity{ID: 1}).Run(ctx, mddb); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if _, err := NewWriter(EnableNonAtomicChunking(), Outbox(otbl)).Emit(&table1Entity{ID: 1}).Run(ctx, mddb); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if _, err := NewWriter(Outbox(otbl)).
+		Put(tbl.simplePut1(&table1Entity{ID: 1, Name: "foo"})).
+		Emit(&table1Entity{ID: 1, Name: "created"}).
+		Emit(&table1Entity{ID: 1, Name: "named"}).
+		Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	its := pending(t)
+	if len(its) != 2 || its[0].Type != "table1Entity" ||
+		aws.StringValue(its[0].Event["f1"].S) != "created" ||
+		aws.StringValue(its[1].Event["f1"].S) != "named" {
+		t.Fatalf("got: %v", its)
+	}
+
+	t.Run("failed write", func(t *testing.T) {
+		b, p, it := tbl.simplePut1(&table1Entity{ID: 1})
+		_, err := NewWriter(Outbox(otbl)).
+			Put(b.WithCondition(e.AttributeNotExists(e.Name("pk"))), p, it).
+			Emit(&table1Entity{ID: 1, Name: "created again"}).
+			Run(ctx, mddb)
+
+		var cfe *ConditionFailedError
+		if !errors.As(err, &cfe) {
+			t.Fatalf("got: %v", err)
+		}
+
+		if its := pending(t); len(its) != 2 {
+			t.Fatalf("got: %v", its)
+		}
+	})
+
+	t.Run("idempotent", func(t *testing.T) {
+		// the same logical write, built and run twice, emits its event once
+		for i := 0; i < 2; i++ {
+			if _, err := NewWriter(EnableIdempotencyTokens(), Outbox(otbl)).
+				Put(tbl.simplePut1(&table1Entity{ID: 2, Name: "bar"})).
+				Emit(&table1Entity{ID: 2, Name: "created"}).
+				Run(ctx, mddb); err != nil {
+				t.Fatalf("got: %v", err)
+			}
+		}
+
+		if its := pending(t); len(its) != 3 {
+			t.Fatalf("got: %v", its)
+		}
+
+		// a write of just the event is run as a single put, that also emits once
+		for i := 0; i < 2; i++ {
+			if _, err := NewWriter(EnableIdempotencyTokens(), Outbox(otbl)).
+				Emit(&table1Entity{ID: 3, Name: "created"}).
+				Run(ctx, mddb); err != nil {
+				t.Fatalf("got: %v", err)
+			}
+		}
+
+		if its := pending(t); len(its) != 4 {
+			t.Fatalf("got: %v", its)
+		}
+	})
+}
+
+// outboxEntity scans outbox items as they are
+type outboxEntity struct{ it *OutboxItem }
+
+func (oe outboxEntity) Item() Item { return oe.it }
+
+func (oe *outboxEntity) FromItem(Item) error { return nil }
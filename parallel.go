@@ -0,0 +1,212 @@
+package ddb
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// ParallelResult is the result of a parallel scan. Close must be called when the result is not
+// iterated until the end, it stops the workers.
+type ParallelResult interface {
+	Result
+	Close()
+}
+
+// ParallelScanner holds a DynamoDB scan that is split into segments which are scanned concurrently
+type ParallelScanner struct {
+	eb       expression.Builder
+	in       dynamodb.ScanInput
+	segments int
+	opts     Options
+}
+
+// ParallelScan sets up a scan of 'segments' segments. When run, every segment is paged through by
+// its own worker and the items of all segments are merged into a single result. Items are returned
+// in no particular order.
+func ParallelScan(b expression.Builder, in dynamodb.ScanInput, segments int, opts ...Option) (q *ParallelScanner) {
+	q = &ParallelScanner{eb: b, in: in, segments: segments}
+	q.opts.Apply(opts...)
+	return
+}
+
+// Run starts the workers and returns a result that merges their pages. Each worker holds at most
+// one page that the result hasn't taken yet, it waits for the result to be iterated before
+// fetching more. When a
+// segment fails the other workers are canceled and the error is returned by the result's Err
+// method. Close the result, or cancel the context, to stop the workers when the result is not
+// iterated until the end. An ExclusiveStartKey can't be used as it can't apply to every segment.
+func (q *ParallelScanner) Run(ctx context.Context, ddb Dynamo) (r ParallelResult, err error) {
+	if q.segments < 1 {
+		return nil, fmt.Errorf("invalid nr of segments: %d", q.segments)
+	}
+
+	if q.in.ExclusiveStartKey != nil {
+		return nil, fmt.Errorf("an exclusive start key is not supported in a parallel scan")
+	}
+
+	expr, err := exprBuild(q.eb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build expression(s): %w", err)
+	}
+
+	in := q.in
+	in.FilterExpression = expr.Filter()
+	in.ProjectionExpression = expr.Projection()
+	in.ExpressionAttributeNames = expr.Names()
+	in.ExpressionAttributeValues = expr.Values()
+	in.TotalSegments = aws.Int64(int64(q.segments))
+	if rcc := q.opts.returnConsumedCapacity(); rcc != nil {
+		in.ReturnConsumedCapacity = rcc
+	}
+	if in.ConsistentRead, err = q.opts.indexConsistentRead(ctx, in.ConsistentRead, in.TableName, in.IndexName); err != nil {
+		return nil, err
+	}
+
+	res := &parallelScanResult{pages: make(chan []map[string]*dynamodb.AttributeValue), pos: -1}
+	ctx, res.cancel = context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+	for seg := 0; seg < q.segments; seg++ {
+		sin := in
+		sin.Segment = aws.Int64(int64(seg))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res.scanSegment(ctx, ddb, &sin)
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(res.pages)
+	}()
+
+	return res, nil
+}
+
+// parallelScanResult is the result of a parallel scan, it receives pages from the workers as
+// the user scans through the results.
+type parallelScanResult struct {
+	tot    int64
+	pages  chan []map[string]*dynamodb.AttributeValue
+	cancel context.CancelFunc
+	items  []map[string]*dynamodb.AttributeValue
+	pos    int
+
+	mu     sync.Mutex
+	err    error
+	closed bool
+}
+
+// scanSegment pages through one segment and sends every page to the result
+func (c *parallelScanResult) scanSegment(ctx context.Context, ddb Dynamo, in *dynamodb.ScanInput) {
+	for pgs := 1; ; pgs++ {
+		out, err := ddb.ScanWithContext(withPage(ctx, pgs), in)
+		if err != nil {
+			c.fail(fmt.Errorf("failed to scan segment %d: %w", aws.Int64Value(in.Segment), err))
+			return
+		}
+
+		addCapacity(ctx, false, out.ConsumedCapacity)
+		atomic.AddInt64(&c.tot, aws.Int64Value(out.Count))
+
+		if len(out.Items) > 0 {
+			select {
+			case c.pages <- out.Items:
+			case <-ctx.Done():
+				c.fail(ctx.Err())
+				return
+			}
+		}
+
+		if out.LastEvaluatedKey == nil {
+			return
+		}
+
+		in.ExclusiveStartKey = out.LastEvaluatedKey
+	}
+}
+
+// fail records the first error and cancels the other workers. Workers that stop because the
+// result was closed don't record an error.
+func (c *parallelScanResult) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil && !c.closed {
+		c.err = err
+	}
+
+	c.cancel()
+}
+
+// Close stops the workers and waits for them to exit. The result has no more items afterwards.
+func (c *parallelScanResult) Close() {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	c.cancel()
+	for range c.pages {
+		// drain the pages of workers that were sending, until all have exited
+	}
+
+	c.items, c.pos = nil, -1
+}
+
+func (c *parallelScanResult) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Len returns the nr of items that all segments have fetched so far
+func (c *parallelScanResult) Len() int64 {
+	return atomic.LoadInt64(&c.tot)
+}
+
+func (c *parallelScanResult) Next() bool {
+	c.pos++
+	for c.pos >= len(c.items) {
+		if c.Err() != nil {
+			return false
+		}
+
+		items, ok := <-c.pages
+		if !ok {
+			c.cancel() // all workers are done, release the context
+			return false
+		}
+
+		c.items, c.pos = items, 0
+	}
+
+	return c.Err() == nil
+}
+
+func (c *parallelScanResult) current() map[string]*dynamodb.AttributeValue {
+	return c.items[c.pos]
+}
+
+func (c *parallelScanResult) Scan(v interface {
+	Itemizer
+	Deitemizer
+}) (err error) {
+	it := v.Item()
+	if err = dynamodbattribute.UnmarshalMap(c.items[c.pos], it); err != nil {
+		return
+	}
+
+	if err = v.FromItem(it); err != nil {
+		return
+	}
+
+	return
+}
@@ -0,0 +1,164 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/gohandle/ddb/internal/ddbtest"
+)
+
+// failingSegmentDynamo fails scans of one segment with 'err'
+type failingSegmentDynamo struct {
+	Dynamo
+	segment int64
+	err     error
+}
+
+func (fddb *failingSegmentDynamo) ScanWithContext(
+	ctx aws.Context,
+	in *dynamodb.ScanInput,
+	opts ...request.Option,
+) (*dynamodb.ScanOutput, error) {
+	if aws.Int64Value(in.Segment) == fddb.segment {
+		return nil, fddb.err
+	}
+
+	return fddb.Dynamo.ScanWithContext(ctx, in, opts...)
+}
+
+// countingScanDynamo counts the scans that are send
+type countingScanDynamo struct {
+	Dynamo
+	calls int64
+}
+
+func (cddb *countingScanDynamo) ScanWithContext(
+	ctx aws.Context,
+	in *dynamodb.ScanInput,
+	opts ...request.Option,
+) (*dynamodb.ScanOutput, error) {
+	atomic.AddInt64(&cddb.calls, 1)
+	return cddb.Dynamo.ScanWithContext(ctx, in, opts...)
+}
+
+func TestParallelScan(t *testing.T) {
+	ctx, tbl := context.Background(), table2(t.Name())
+	mddb := ddbtest.MemDB(t, tbl.createInput())
+
+	w := NewWriter(EnableBatchWrites())
+	for i := 0; i < 50; i++ {
+		w.Put(tbl.Put1(&table2Entity{ID: i, Kind: i % 3}))
+	}
+
+	if _, err := w.Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	in := dynamodb.ScanInput{TableName: aws.String(string(tbl)), Limit: aws.Int64(3)}
+	r, err := ParallelScan(e.NewBuilder().WithFilter(e.Name("kind").Equal(e.Value(1))), in, 4).Run(ctx, mddb)
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	var ids []int
+	for r.Next() {
+		var ent table2Entity
+		if err = r.Scan(&ent); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		ids = append(ids, ent.ID)
+	}
+
+	if r.Err() != nil || r.Len() != 17 || len(ids) != 17 {
+		t.Fatalf("got: %v %d %v", r.Err(), r.Len(), ids)
+	}
+
+	sort.Ints(ids)
+	for i, id := range ids {
+		if id != i*3+1 {
+			t.Fatalf("got: %v", ids)
+		}
+	}
+
+	t.Run("unread pages", func(t *testing.T) {
+		cddb := &countingScanDynamo{Dynamo: mddb}
+		r, err := ParallelScan(e.NewBuilder(), in, 4).Run(ctx, cddb)
+		if err != nil {
+			t.Fatalf("got: %v", err)
+		}
+		defer r.Close()
+
+		if !r.Next() {
+			t.Fatalf("got: %v", r.Err())
+		}
+
+		// every worker fetched a page, only the one that was taken fetched its next
+		time.Sleep(20 * time.Millisecond)
+		if n := atomic.LoadInt64(&cddb.calls); n != 5 {
+			t.Fatalf("got: %v", n)
+		}
+	})
+
+	t.Run("failing segment", func(t *testing.T) {
+		experr := errors.New("boom")
+		r, err := ParallelScan(e.NewBuilder(), in, 4).Run(ctx, &failingSegmentDynamo{mddb, 2, experr})
+		if err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		for r.Next() {
+		}
+
+		if !errors.Is(r.Err(), experr) {
+			t.Fatalf("got: %v", r.Err())
+		}
+	})
+
+	t.Run("invalid segments", func(t *testing.T) {
+		if _, err := ParallelScan(e.NewBuilder(), in, 0).Run(ctx, mddb); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+
+	t.Run("exclusive start key", func(t *testing.T) {
+		in := in
+		in.ExclusiveStartKey = map[string]*dynamodb.AttributeValue{"pk": {S: aws.String("e1")}}
+		if _, err := ParallelScan(e.NewBuilder(), in, 4).Run(ctx, mddb); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+
+	t.Run("close early", func(t *testing.T) {
+		before := runtime.NumGoroutine()
+
+		in := in
+		in.Limit = aws.Int64(1)
+		r, err := ParallelScan(e.NewBuilder(), in, 4).Run(ctx, mddb)
+		if err != nil || !r.Next() {
+			t.Fatalf("got: %v %v", err, r.Err())
+		}
+
+		r.Close()
+		if r.Next() || r.Err() != nil {
+			t.Fatalf("got: %v", r.Err())
+		}
+
+		for i := 0; runtime.NumGoroutine() > before; i++ {
+			if i > 100 {
+				t.Fatalf("workers leaked, got: %d > %d", runtime.NumGoroutine(), before)
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
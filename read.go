@@ -12,10 +12,15 @@ import (
 type Reader struct {
 	reads []*dynamodb.TransactGetItem
 	err   error
+	opts  Options
 }
 
 // NewReader inits an empty read
-func NewReader() *Reader { return &Reader{} }
+func NewReader(opts ...Option) (r *Reader) {
+	r = &Reader{}
+	r.opts.Apply(opts...)
+	return r
+}
 
 // Get starts a read and adds one get operation
 func Get(eb expression.Builder, get dynamodb.Get, key Itemizer) *Reader {
@@ -32,7 +37,7 @@ func (r *Reader) Get(eb expression.Builder, get dynamodb.Get, key Itemizer) *Rea
 
 	pk, sk := k.Keys()
 	get.Key = mapFilter(get.Key, pk, sk)
-	get.ProjectionExpression = expr.Condition()
+	get.ProjectionExpression = expr.Projection()
 	get.ExpressionAttributeNames = expr.Names()
 	r.reads = append(r.reads, &dynamodb.TransactGetItem{Get: &get})
 	return r
@@ -45,16 +50,23 @@ func (r *Reader) Run(ctx context.Context, ddb Dynamo) (res Result, err error) {
 	}
 
 	if len(r.reads) == 1 {
-		return readSingle(ctx, ddb, r.reads[0])
+		return readSingle(ctx, ddb, r.reads[0], r.opts.returnConsumedCapacity(), r.opts.consistentRead(ctx))
+	}
+
+	if r.opts.enableBatchReads {
+		return r.runBatch(ctx, ddb)
 	}
 
 	var out *dynamodb.TransactGetItemsOutput
 	if out, err = ddb.TransactGetItemsWithContext(ctx, &dynamodb.TransactGetItemsInput{
-		TransactItems: r.reads,
+		TransactItems:          r.reads,
+		ReturnConsumedCapacity: r.opts.returnConsumedCapacity(),
 	}); err != nil {
 		return nil, fmt.Errorf("failed to transact: %w", err)
 	}
 
+	addCapacity(ctx, false, out.ConsumedCapacity...)
+
 	if len(out.Responses) < 0 {
 		return emptyResult{}, nil
 	}
@@ -0,0 +1,136 @@
+package ddb
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ErrUnregisteredItem is returned when an item doesn't match any of the entities in a registry
+var ErrUnregisteredItem = errors.New("item doesn't match a registered entity")
+
+// AnyEntity is an entity that can be scanned from a result without knowing its type up front
+type AnyEntity interface {
+	Itemizer
+	Deitemizer
+}
+
+// Constructor returns a new, empty, entity that an item can be scanned into
+type Constructor func() AnyEntity
+
+// registration maps items with an attribute value, or value prefix, onto an entity
+type registration struct {
+	attr  string
+	value string
+	new   Constructor
+}
+
+// Registry maps the items of a single-table design onto the entities they store, such that an
+// item collection with several types of entities can be decoded in one go. Items are matched by
+// a discriminator attribute first and by the longest key prefix otherwise.
+type Registry struct {
+	types    []registration
+	prefixes []registration
+}
+
+// NewRegistry inits an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Type registers the entity for items with a discriminator attribute that is equal to 'value'.
+// Both string and number attributes are compared by their string representation.
+func (reg *Registry) Type(attr, value string, c Constructor) *Registry {
+	reg.types = append(reg.types, registration{attr, value, c})
+	return reg
+}
+
+// Prefix registers the entity for items with a string attribute, usually the sort key, that
+// starts with 'prefix'. For example: "USER#".
+func (reg *Registry) Prefix(attr, prefix string, c Constructor) *Registry {
+	reg.prefixes = append(reg.prefixes, registration{attr, prefix, c})
+	sort.SliceStable(reg.prefixes, func(i, j int) bool {
+		return len(reg.prefixes[i].value) > len(reg.prefixes[j].value)
+	})
+
+	return reg
+}
+
+// lookup returns the constructor for an item, or nil if it isn't registered
+func (reg *Registry) lookup(item map[string]*dynamodb.AttributeValue) Constructor {
+	for _, t := range reg.types {
+		if av, ok := item[t.attr]; ok && av != nil &&
+			((av.S != nil && *av.S == t.value) || (av.N != nil && *av.N == t.value)) {
+			return t.new
+		}
+	}
+
+	for _, p := range reg.prefixes {
+		if av, ok := item[p.attr]; ok && av != nil && strings.HasPrefix(aws.StringValue(av.S), p.value) {
+			return p.new
+		}
+	}
+
+	return nil
+}
+
+// attrs returns the names of the attributes that items are matched by
+func (reg *Registry) attrs() (names []string) {
+	for _, rs := range [][]registration{reg.types, reg.prefixes} {
+		for _, r := range rs {
+			names = append(names, r.attr)
+		}
+	}
+	return
+}
+
+// currentResult is implemented by results that expose the item they are positioned at
+type currentResult interface {
+	current() map[string]*dynamodb.AttributeValue
+}
+
+// ScanAny scans the item that the result is positioned at, after a call to Next, into a new
+// entity of the registered type.
+func (reg *Registry) ScanAny(r Result) (AnyEntity, error) {
+	cr, ok := r.(currentResult)
+	if !ok {
+		return nil, fmt.Errorf("result of type %T doesn't expose its items", r)
+	}
+
+	item := cr.current()
+	c := reg.lookup(item)
+	if c == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnregisteredItem, keyString(mapFilter(item, reg.attrs()...)))
+	}
+
+	ent := c()
+	if err := r.Scan(ent); err != nil {
+		return nil, err
+	}
+
+	return ent, nil
+}
+
+// UnmarshalCollection runs through all items of the result and scans each of them into a new
+// entity of the registered type. It consumes the result in the process. Use a type switch on
+// the entities to tell them apart.
+func (reg *Registry) UnmarshalCollection(r Result) (ents []AnyEntity, err error) {
+	for r.Next() {
+		var ent AnyEntity
+		if ent, err = reg.ScanAny(r); err != nil {
+			return nil, err
+		}
+
+		ents = append(ents, ent)
+	}
+
+	if err = r.Err(); err != nil {
+		return nil, err
+	}
+
+	return
+}
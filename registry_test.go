@@ -0,0 +1,109 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/gohandle/ddb/internal/ddbtest"
+)
+
+// collectionItem stores teams and their members in the same partition of table2
+type collectionItem struct {
+	PK   string `dynamodbav:"pk"`
+	SK   string `dynamodbav:"sk"`
+	Type string `dynamodbav:"type,omitempty"`
+	Name string `dynamodbav:"name"`
+}
+
+func (collectionItem) Keys() (pk, sk string) { return "pk", "sk" }
+
+type team struct{ Name string }
+
+func (t team) Item() Item {
+	return &collectionItem{PK: "TEAM#" + t.Name, SK: "TEAM#" + t.Name, Type: "team", Name: t.Name}
+}
+
+func (t *team) FromItem(it Item) error {
+	t.Name = it.(*collectionItem).Name
+	return nil
+}
+
+type member struct{ Team, Name string }
+
+func (m member) Item() Item {
+	return &collectionItem{PK: "TEAM#" + m.Team, SK: "MEMBER#" + m.Name, Name: m.Name}
+}
+
+func (m *member) FromItem(it Item) error {
+	m.Team, m.Name = strings.TrimPrefix(it.(*collectionItem).PK, "TEAM#"), it.(*collectionItem).Name
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	ctx, tbl := context.Background(), table2(t.Name())
+	mddb := ddbtest.MemDB(t, tbl.createInput())
+
+	put := func(ent Itemizer) (b e.Builder, p dynamodb.Put, it Itemizer) {
+		p.SetTableName(string(tbl))
+		return b, p, ent
+	}
+
+	if _, err := NewWriter().
+		Put(put(&team{Name: "a"})).
+		Put(put(&member{Team: "a", Name: "x"})).
+		Put(put(&member{Team: "a", Name: "y"})).
+		Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	reg := NewRegistry().
+		Type("type", "team", func() AnyEntity { return &team{} }).
+		Prefix("sk", "MEMBER#", func() AnyEntity { return &member{} })
+
+	qry := func() *Querier {
+		return Query(e.NewBuilder().WithKeyCondition(e.Key("pk").Equal(e.Value("TEAM#a"))),
+			dynamodb.QueryInput{TableName: aws.String(string(tbl)), Limit: aws.Int64(2)})
+	}
+
+	r, err := qry().Run(ctx, mddb)
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	ents, err := reg.UnmarshalCollection(r)
+	if err != nil || len(ents) != 3 {
+		t.Fatalf("got: %v %v", ents, err)
+	}
+
+	var tm *team
+	var members []string
+	for _, ent := range ents {
+		switch ent := ent.(type) {
+		case *team:
+			tm = ent
+		case *member:
+			members = append(members, ent.Team+"/"+ent.Name)
+		}
+	}
+
+	if tm == nil || tm.Name != "a" || strings.Join(members, ",") != "a/x,a/y" {
+		t.Fatalf("got: %v %v", tm, members)
+	}
+
+	t.Run("unregistered", func(t *testing.T) {
+		r, err := qry().Run(ctx, mddb)
+		if err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		if _, err = NewRegistry().Type("type", "team", func() AnyEntity { return &team{} }).
+			UnmarshalCollection(r); !errors.Is(err, ErrUnregisteredItem) {
+			t.Fatalf("got: %v", err)
+		}
+	})
+}
@@ -47,6 +47,10 @@ func (c *result) Scan(v interface {
 	return
 }
 
+func (c *result) current() map[string]*dynamodb.AttributeValue {
+	return c.items[c.pos]
+}
+
 // emptyResult is a result without items
 type emptyResult struct{}
 
@@ -59,3 +63,5 @@ func (c emptyResult) Scan(v interface {
 }) (err error) {
 	return
 }
+
+func (c emptyResult) current() map[string]*dynamodb.AttributeValue { return nil }
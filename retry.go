@@ -0,0 +1,246 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// RetryPolicy configures how operations are retried by RetryingDynamo
+type RetryPolicy struct {
+	// MaxAttempts is the maximum nr of times an operation is attempted, including the first time
+	MaxAttempts int
+
+	// OperationMaxAttempts overwrites MaxAttempts for specific operations, keyed by the name of
+	// the DynamoDB operation. For example: "TransactWriteItems".
+	OperationMaxAttempts map[string]int
+
+	// BaseDelay and MaxDelay bound the exponential backoff (with full jitter) between attempts
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Retryable decides if an error is worth retrying, if nil IsRetryable is used
+	Retryable func(err error) bool
+
+	// OnRetry is called before every retry with the operation name, the attempt that failed
+	// (starting at 1), how long it will wait and the error that caused the retry.
+	OnRetry func(op string, attempt int, delay time.Duration, err error)
+}
+
+// DefaultRetryPolicy is a retry policy with sensible defaults
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   50 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// maxAttempts returns the max attempts for the named operation
+func (p RetryPolicy) maxAttempts(op string) int {
+	if n, ok := p.OperationMaxAttempts[op]; ok {
+		return n
+	}
+
+	return p.MaxAttempts
+}
+
+// IsRetryable returns true for errors that are caused by throttling or transaction conflicts and
+// can therefore be expected to succeed when the operation is tried again later.
+func IsRetryable(err error) bool {
+	var tce *dynamodb.TransactionCanceledException
+	if errors.As(err, &tce) {
+		var retry bool
+		for _, r := range tce.CancellationReasons {
+			switch aws.StringValue(r.Code) {
+			case TxReasonNone:
+			case TxReasonTransactionConflict, TxReasonThrottlingError, TxReasonProvisionedThroughputExceeded:
+				retry = true
+			default:
+				return false // other reasons will fail the same way again
+			}
+		}
+
+		return retry
+	}
+
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+
+	switch aerr.Code() {
+	case dynamodb.ErrCodeProvisionedThroughputExceededException,
+		dynamodb.ErrCodeRequestLimitExceeded,
+		dynamodb.ErrCodeTransactionInProgressException,
+		dynamodb.ErrCodeInternalServerError,
+		"ThrottlingException":
+		return true
+	}
+
+	return false
+}
+
+// retryingDynamo implements the Dynamo interface but retries operations that failed
+type retryingDynamo struct {
+	ddb    Dynamo
+	policy RetryPolicy
+}
+
+// retry calls 'f' until it succeeds, the error is not retryable, the max attempts is reached or
+// the next attempt would happen after the context's deadline.
+func (rddb *retryingDynamo) retry(ctx context.Context, op string, f func() error) (err error) {
+	retryable := rddb.policy.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+
+	max := rddb.policy.maxAttempts(op)
+	for attempt := 1; ; attempt++ {
+		if err = f(); err == nil || !retryable(err) || attempt >= max {
+			return err
+		}
+
+		delay := backoff(attempt, rddb.policy.BaseDelay, rddb.policy.MaxDelay)
+		if dl, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(dl) {
+			return err
+		}
+
+		if rddb.policy.OnRetry != nil {
+			rddb.policy.OnRetry(op, attempt, delay, err)
+		}
+
+		if serr := sleepCtx(ctx, delay); serr != nil {
+			return err
+		}
+	}
+}
+
+func (rddb *retryingDynamo) PutItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.PutItemInput,
+	opts ...request.Option,
+) (out *dynamodb.PutItemOutput, err error) {
+	err = rddb.retry(ctx, "PutItem", func() (err error) {
+		out, err = rddb.ddb.PutItemWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (rddb *retryingDynamo) GetItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.GetItemInput,
+	opts ...request.Option,
+) (out *dynamodb.GetItemOutput, err error) {
+	err = rddb.retry(ctx, "GetItem", func() (err error) {
+		out, err = rddb.ddb.GetItemWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (rddb *retryingDynamo) DeleteItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.DeleteItemInput,
+	opts ...request.Option,
+) (out *dynamodb.DeleteItemOutput, err error) {
+	err = rddb.retry(ctx, "DeleteItem", func() (err error) {
+		out, err = rddb.ddb.DeleteItemWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (rddb *retryingDynamo) UpdateItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.UpdateItemInput,
+	opts ...request.Option,
+) (out *dynamodb.UpdateItemOutput, err error) {
+	err = rddb.retry(ctx, "UpdateItem", func() (err error) {
+		out, err = rddb.ddb.UpdateItemWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (rddb *retryingDynamo) QueryWithContext(
+	ctx aws.Context,
+	in *dynamodb.QueryInput,
+	opts ...request.Option,
+) (out *dynamodb.QueryOutput, err error) {
+	err = rddb.retry(ctx, "Query", func() (err error) {
+		out, err = rddb.ddb.QueryWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (rddb *retryingDynamo) TransactWriteItemsWithContext(
+	ctx aws.Context,
+	in *dynamodb.TransactWriteItemsInput,
+	opts ...request.Option,
+) (out *dynamodb.TransactWriteItemsOutput, err error) {
+	err = rddb.retry(ctx, "TransactWriteItems", func() (err error) {
+		out, err = rddb.ddb.TransactWriteItemsWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (rddb *retryingDynamo) TransactGetItemsWithContext(
+	ctx aws.Context,
+	in *dynamodb.TransactGetItemsInput,
+	opts ...request.Option,
+) (out *dynamodb.TransactGetItemsOutput, err error) {
+	err = rddb.retry(ctx, "TransactGetItems", func() (err error) {
+		out, err = rddb.ddb.TransactGetItemsWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (rddb *retryingDynamo) ScanWithContext(
+	ctx aws.Context,
+	in *dynamodb.ScanInput,
+	opts ...request.Option,
+) (out *dynamodb.ScanOutput, err error) {
+	err = rddb.retry(ctx, "Scan", func() (err error) {
+		out, err = rddb.ddb.ScanWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (rddb *retryingDynamo) BatchGetItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.BatchGetItemInput,
+	opts ...request.Option,
+) (out *dynamodb.BatchGetItemOutput, err error) {
+	err = rddb.retry(ctx, "BatchGetItem", func() (err error) {
+		out, err = rddb.ddb.BatchGetItemWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+func (rddb *retryingDynamo) BatchWriteItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.BatchWriteItemInput,
+	opts ...request.Option,
+) (out *dynamodb.BatchWriteItemOutput, err error) {
+	err = rddb.retry(ctx, "BatchWriteItem", func() (err error) {
+		out, err = rddb.ddb.BatchWriteItemWithContext(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// RetryingDynamo returns a dynamo interface that retries operations that failed because of
+// throttling or transaction conflicts according to the provided policy.
+func RetryingDynamo(ddb Dynamo, policy RetryPolicy) Dynamo {
+	return &retryingDynamo{ddb, policy}
+}
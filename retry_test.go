@@ -0,0 +1,123 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// failingDynamo fails the first 'n' put operations with 'err'
+type failingDynamo struct {
+	Dynamo
+	n     int
+	calls int
+	err   error
+}
+
+func (fddb *failingDynamo) PutItemWithContext(
+	ctx aws.Context,
+	in *dynamodb.PutItemInput,
+	opts ...request.Option,
+) (*dynamodb.PutItemOutput, error) {
+	fddb.calls++
+	if fddb.calls <= fddb.n {
+		return nil, fddb.err
+	}
+
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func TestIsRetryable(t *testing.T) {
+	for i, c := range []struct {
+		err error
+		exp bool
+	}{
+		{errors.New("foo"), false},
+		{awserr.New(dynamodb.ErrCodeProvisionedThroughputExceededException, "", nil), true},
+		{awserr.New("ThrottlingException", "", nil), true},
+		{awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "", nil), false},
+		{&dynamodb.TransactionCanceledException{CancellationReasons: []*dynamodb.CancellationReason{
+			{Code: aws.String(TxReasonNone)},
+			{Code: aws.String(TxReasonTransactionConflict)},
+		}}, true},
+		{&dynamodb.TransactionCanceledException{CancellationReasons: []*dynamodb.CancellationReason{
+			{Code: aws.String(TxReasonConditionalCheckFailed)},
+			{Code: aws.String(TxReasonTransactionConflict)},
+		}}, false},
+	} {
+		if act := IsRetryable(c.err); act != c.exp {
+			t.Fatalf("%d: got: %v", i, act)
+		}
+	}
+}
+
+func TestRetryingDynamo(t *testing.T) {
+	ctx := context.Background()
+	throttled := awserr.New("ThrottlingException", "slow down", nil)
+
+	var retries []int
+	policy := RetryPolicy{
+		MaxAttempts:          3,
+		OperationMaxAttempts: map[string]int{"PutItem": 4},
+		BaseDelay:            time.Millisecond,
+		MaxDelay:             time.Millisecond * 5,
+		OnRetry: func(op string, attempt int, delay time.Duration, err error) {
+			if op != "PutItem" || err != throttled {
+				t.Fatalf("got: %v %v", op, err)
+			}
+
+			retries = append(retries, attempt)
+		},
+	}
+
+	fddb := &failingDynamo{n: 3, err: throttled}
+	if _, err := RetryingDynamo(fddb, policy).PutItemWithContext(ctx, &dynamodb.PutItemInput{}); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if fddb.calls != 4 || len(retries) != 3 {
+		t.Fatalf("got: %v %v", fddb.calls, retries)
+	}
+
+	fddb = &failingDynamo{n: 10, err: throttled}
+	if _, err := RetryingDynamo(fddb, policy).PutItemWithContext(ctx, &dynamodb.PutItemInput{}); err != throttled {
+		t.Fatalf("got: %v", err)
+	}
+
+	if fddb.calls != 4 {
+		t.Fatalf("got: %v", fddb.calls)
+	}
+
+	fddb = &failingDynamo{n: 10, err: errors.New("foo")}
+	if _, err := RetryingDynamo(fddb, policy).PutItemWithContext(ctx, &dynamodb.PutItemInput{}); err == nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if fddb.calls != 1 {
+		t.Fatalf("got: %v", fddb.calls)
+	}
+
+	t.Run("respect deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+		defer cancel()
+
+		policy.BaseDelay, policy.MaxDelay = time.Second, time.Second
+		policy.OnRetry = nil
+
+		t0 := time.Now()
+		fddb = &failingDynamo{n: 10, err: throttled}
+		if _, err := RetryingDynamo(fddb, policy).PutItemWithContext(ctx, &dynamodb.PutItemInput{}); err != throttled {
+			t.Fatalf("got: %v", err)
+		}
+
+		if act := time.Since(t0); act > time.Millisecond*100 {
+			t.Fatalf("got: %v", act)
+		}
+	})
+}
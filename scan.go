@@ -11,14 +11,17 @@ import (
 
 // Scanner holds a DynamoDB query
 type Scanner struct {
-	res *scanResult
-	eb  expression.Builder
+	res    *scanResult
+	eb     expression.Builder
+	opts   Options
+	cursor string
 }
 
 // Scan sets up a scanner that can be run to fetch
-func Scan(b expression.Builder, in dynamodb.ScanInput) (q *Scanner) {
+func Scan(b expression.Builder, in dynamodb.ScanInput, opts ...Option) (q *Scanner) {
 	q = new(Scanner)
-	q.res = &scanResult{pos: -1}
+	q.opts.Apply(opts...)
+	q.res = &scanResult{pos: -1, opts: q.opts}
 	q.res.in = &in
 	q.eb = b
 	return
@@ -37,27 +40,71 @@ func (q *Scanner) Run(ctx context.Context, ddb Dynamo) (r Result, err error) {
 	q.res.in.ProjectionExpression = expr.Projection()
 	q.res.in.ExpressionAttributeNames = expr.Names()
 	q.res.in.ExpressionAttributeValues = expr.Values()
-	return q.res, q.res.init()
+	if rcc := q.opts.returnConsumedCapacity(); rcc != nil {
+		q.res.in.ReturnConsumedCapacity = rcc
+	}
+	if q.res.in.ConsistentRead, err = q.opts.indexConsistentRead(
+		ctx, q.res.in.ConsistentRead, q.res.in.TableName, q.res.in.IndexName); err != nil {
+		return nil, err
+	}
+
+	var start cursor
+	if q.cursor != "" {
+		if start, err = decodeCursor(q.opts, q.cursor, q.res.in.TableName, q.res.in.IndexName); err != nil {
+			return nil, err
+		}
+
+		q.res.in.ExclusiveStartKey = start.startKey()
+	}
+
+	if err = q.res.init(); err != nil {
+		return q.res, err
+	}
+
+	q.res.pos = start.Offset - 1 // skip the items that were returned before the cursor was created
+	return q.res, nil
+}
+
+// MaxItems makes the scan return at most 'n' items, counted after the filter is applied. Pages
+// are fetched until the items are returned or there are no more pages, the result's Cursor then
+// continues after the last item (see PagedResult).
+func (q *Scanner) MaxItems(n int) *Scanner {
+	q.res.max = n
+	return q
+}
+
+// StartAt makes the scan continue from a cursor that was returned by the Cursor method of an
+// earlier result (see PagedResult). The input must be the same, the page that the cursor was
+// created in is fetched again. An empty cursor starts at the beginning.
+func (q *Scanner) StartAt(cursor string) *Scanner {
+	q.cursor = cursor
+	return q
 }
 
 // scanResult is a result that is returned when a scan operation
 // is run. It will automatically get more pages as the user scans
 // through the results.
 type scanResult struct {
-	ctx context.Context
-	in  *dynamodb.ScanInput
-	out *dynamodb.ScanOutput
-	tot int64
-	ddb Dynamo
-	err error
-	pos int
+	ctx  context.Context
+	in   *dynamodb.ScanInput
+	out  *dynamodb.ScanOutput
+	tot  int64
+	ddb  Dynamo
+	err  error
+	pos  int
+	pgs  int
+	max  int
+	cnt  int
+	opts Options
 }
 
 func (c *scanResult) init() (err error) {
-	if c.out, err = c.ddb.ScanWithContext(c.ctx, c.in); err != nil {
+	c.pgs = 1
+	if c.out, err = c.ddb.ScanWithContext(withPage(c.ctx, c.pgs), c.in); err != nil {
 		return err
 	}
 
+	addCapacity(c.ctx, false, c.out.ConsumedCapacity)
 	c.tot = *c.out.Count
 	return nil
 }
@@ -71,30 +118,34 @@ func (c *scanResult) Err() error {
 }
 
 func (c *scanResult) Next() bool {
+	if c.out == nil || c.err != nil {
+		return false
+	}
 
-	// we got some query output, start with pos iteration
+	if c.max > 0 && c.cnt >= c.max {
+		return false // the item budget is spent, the cursor continues from here
+	}
+
+	// pages can be empty when the filter matched none of the evaluated items, so we keep
+	// fetching until there is an item or there are no more pages.
 	c.pos++
-	if c.out != nil && c.pos >= len(c.out.Items) {
+	for c.pos >= len(c.out.Items) {
 		if c.out.LastEvaluatedKey == nil {
 			return false // fully done
 		}
 
-		// else we prep the cursor for a new query
-		if c.out.LastEvaluatedKey != nil {
-			c.pos = 0
-			c.in.ExclusiveStartKey = c.out.LastEvaluatedKey
-			c.out = nil
-		}
-	}
-
-	// no out, run again
-	if c.out == nil {
-		if c.out, c.err = c.ddb.ScanWithContext(c.ctx, c.in); c.err != nil {
+		c.pgs++
+		c.in.ExclusiveStartKey = c.out.LastEvaluatedKey
+		if c.out, c.err = c.ddb.ScanWithContext(withPage(c.ctx, c.pgs), c.in); c.err != nil {
 			return false
 		}
+
+		addCapacity(c.ctx, false, c.out.ConsumedCapacity)
 		c.tot += *c.out.Count
+		c.pos = 0
 	}
 
+	c.cnt++
 	return true
 }
 
@@ -113,3 +164,17 @@ func (c *scanResult) Scan(v interface {
 
 	return
 }
+
+func (c *scanResult) current() map[string]*dynamodb.AttributeValue {
+	return c.out.Items[c.pos]
+}
+
+// Cursor returns an opaque token of the position after the item that was last returned by Next
+func (c *scanResult) Cursor() (string, error) {
+	if c.out == nil {
+		return "", fmt.Errorf("no page to create a cursor for: %w", c.err)
+	}
+
+	return pageCursor(c.opts, c.in.TableName, c.in.IndexName,
+		c.in.ExclusiveStartKey, c.out.LastEvaluatedKey, c.pos, len(c.out.Items))
+}
@@ -0,0 +1,393 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// schemaPollInterval is the time between checks when waiting for a new table to become active
+var schemaPollInterval = 500 * time.Millisecond
+
+// TableDynamo describes the part of the official DynamoDB interface that manages tables
+type TableDynamo interface {
+	CreateTableWithContext(
+		aws.Context,
+		*dynamodb.CreateTableInput,
+		...request.Option,
+	) (*dynamodb.CreateTableOutput, error)
+
+	DescribeTableWithContext(
+		aws.Context,
+		*dynamodb.DescribeTableInput,
+		...request.Option,
+	) (*dynamodb.DescribeTableOutput, error)
+}
+
+// Index describes a secondary index of a table
+type Index struct {
+	Name string
+
+	// PK and SK name the key attributes of the index, SK may be empty. Local indexes always
+	// share the partition key of the table so PK is ignored for them.
+	PK, SK string
+	Local  bool
+
+	// Projection is the projection type of the index, ALL if empty. NonKeyAttributes lists the
+	// attributes that are projected with the INCLUDE type.
+	Projection       string
+	NonKeyAttributes []string
+}
+
+// Indexer can be implemented by items to declare the secondary indexes of their table
+type Indexer interface {
+	Indexes() []Index
+}
+
+// Schema describes the table that stores one type of item: its keys, the types of the key
+// attributes and its secondary indexes.
+type Schema struct {
+	Table      string
+	PK, SK     string
+	Indexes    []Index
+	Attributes map[string]string
+}
+
+// NewSchema derives the schema of a table from its item type. The key attributes are named by
+// the Keys method, their types are inferred from the fields of the item and their dynamodbav
+// tags. Strings and times are stored as S, numbers as N and byte slices as B.
+func NewSchema(table string, item Item) (s *Schema, err error) {
+	s = &Schema{Table: table, Attributes: map[string]string{}}
+	s.PK, s.SK = item.Keys()
+	if idxr, ok := item.(Indexer); ok {
+		s.Indexes = idxr.Indexes()
+	}
+
+	for _, idx := range s.Indexes {
+		if idx.Local && idx.SK == "" {
+			return nil, fmt.Errorf("local index '%s' must have a sort key", idx.Name)
+		}
+	}
+
+	types := map[string]string{}
+	if err = attributeTypes(reflect.TypeOf(item), types); err != nil {
+		return nil, err
+	}
+
+	for _, name := range s.keyAttributes() {
+		switch types[name] {
+		case dynamodb.ScalarAttributeTypeS, dynamodb.ScalarAttributeTypeN, dynamodb.ScalarAttributeTypeB:
+			s.Attributes[name] = types[name]
+		case "":
+			return nil, fmt.Errorf("key attribute '%s' is not a field of %T", name, item)
+		default:
+			return nil, fmt.Errorf("key attribute '%s' of %T must be a string, number or binary", name, item)
+		}
+	}
+
+	return s, nil
+}
+
+// keyAttributes returns the names of the key attributes of the table and its indexes
+func (s *Schema) keyAttributes() (names []string) {
+	seen := map[string]bool{}
+	for _, name := range []string{s.PK, s.SK} {
+		if name != "" && !seen[name] {
+			seen[name], names = true, append(names, name)
+		}
+	}
+
+	for _, idx := range s.Indexes {
+		for _, name := range []string{idx.PK, idx.SK} {
+			if name != "" && !seen[name] && !(idx.Local && name == idx.PK) {
+				seen[name], names = true, append(names, name)
+			}
+		}
+	}
+
+	return
+}
+
+// attributeTypes infers the attribute type of each field of a struct type the way the
+// dynamodbattribute package encodes it. Fields of other types are recorded as "?".
+func attributeTypes(t reflect.Type, types map[string]string) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return fmt.Errorf("item must be a struct, got: %s", t)
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("dynamodbav"), ",")
+		name, opts := tag[0], tag[1:]
+		if name == "-" || (f.PkgPath != "" && !f.Anonymous) {
+			continue
+		}
+
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			if err := attributeTypes(ft, types); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		types[name] = attributeType(ft, opts)
+	}
+
+	return nil
+}
+
+// attributeType returns the scalar attribute type that a field type is encoded as
+func attributeType(t reflect.Type, opts []string) string {
+	hasOpt := func(opt string) bool {
+		for _, o := range opts {
+			if o == opt {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}) && hasOpt("unixtime"):
+		return dynamodb.ScalarAttributeTypeN
+	case t == reflect.TypeOf(time.Time{}):
+		return dynamodb.ScalarAttributeTypeS
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() == reflect.Uint8:
+		return dynamodb.ScalarAttributeTypeB
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return dynamodb.ScalarAttributeTypeS
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if hasOpt("string") {
+			return dynamodb.ScalarAttributeTypeS
+		}
+		return dynamodb.ScalarAttributeTypeN
+	}
+
+	return "?" // not a scalar type that can be used as a key
+}
+
+// keySchema returns the key schema for a partition and (optional) sort key
+func keySchema(pk, sk string) []*dynamodb.KeySchemaElement {
+	ks := []*dynamodb.KeySchemaElement{{AttributeName: aws.String(pk), KeyType: aws.String(dynamodb.KeyTypeHash)}}
+	if sk != "" {
+		ks = append(ks, &dynamodb.KeySchemaElement{AttributeName: aws.String(sk), KeyType: aws.String(dynamodb.KeyTypeRange)})
+	}
+	return ks
+}
+
+// projection returns the projection of an index
+func (idx Index) projection() *dynamodb.Projection {
+	p := &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)}
+	if idx.Projection != "" {
+		p.ProjectionType = aws.String(idx.Projection)
+	}
+
+	if len(idx.NonKeyAttributes) > 0 {
+		p.NonKeyAttributes = aws.StringSlice(idx.NonKeyAttributes)
+	}
+
+	return p
+}
+
+// CreateTableInput returns the input that creates the table. The table, and its global
+// indexes, are billed per request.
+func (s *Schema) CreateTableInput() *dynamodb.CreateTableInput {
+	in := &dynamodb.CreateTableInput{
+		TableName:   aws.String(s.Table),
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		KeySchema:   keySchema(s.PK, s.SK),
+	}
+
+	names := s.keyAttributes()
+	sort.Strings(names)
+	for _, name := range names {
+		in.AttributeDefinitions = append(in.AttributeDefinitions, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(name),
+			AttributeType: aws.String(s.Attributes[name]),
+		})
+	}
+
+	for _, idx := range s.Indexes {
+		if idx.Local {
+			in.LocalSecondaryIndexes = append(in.LocalSecondaryIndexes, &dynamodb.LocalSecondaryIndex{
+				IndexName:  aws.String(idx.Name),
+				KeySchema:  keySchema(s.PK, idx.SK),
+				Projection: idx.projection(),
+			})
+			continue
+		}
+
+		in.GlobalSecondaryIndexes = append(in.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndex{
+			IndexName:  aws.String(idx.Name),
+			KeySchema:  keySchema(idx.PK, idx.SK),
+			Projection: idx.projection(),
+		})
+	}
+
+	return in
+}
+
+// Check compares the schema with the description of an existing table. It returns a
+// SchemaMismatchError that lists every difference. Indexes that the table has but the
+// schema doesn't declare are ignored.
+func (s *Schema) Check(desc *dynamodb.TableDescription) error {
+	var diffs []string
+	if act := keySchemaString(desc.KeySchema); act != keySchemaString(keySchema(s.PK, s.SK)) {
+		diffs = append(diffs, fmt.Sprintf("table key is %s, expected %s", act, keySchemaString(keySchema(s.PK, s.SK))))
+	}
+
+	types := map[string]string{}
+	for _, ad := range desc.AttributeDefinitions {
+		types[aws.StringValue(ad.AttributeName)] = aws.StringValue(ad.AttributeType)
+	}
+
+	for _, name := range s.keyAttributes() {
+		switch act := types[name]; {
+		case act == "":
+			diffs = append(diffs, fmt.Sprintf("attribute '%s' is not defined, expected type '%s'", name, s.Attributes[name]))
+		case act != s.Attributes[name]:
+			diffs = append(diffs, fmt.Sprintf("attribute '%s' has type '%s', expected '%s'", name, act, s.Attributes[name]))
+		}
+	}
+
+	gsis, lsis := map[string]string{}, map[string]string{}
+	for _, gsi := range desc.GlobalSecondaryIndexes {
+		gsis[aws.StringValue(gsi.IndexName)] = keySchemaString(gsi.KeySchema)
+	}
+	for _, lsi := range desc.LocalSecondaryIndexes {
+		lsis[aws.StringValue(lsi.IndexName)] = keySchemaString(lsi.KeySchema)
+	}
+
+	for _, idx := range s.Indexes {
+		kind, idxs, exp := "global", gsis, keySchemaString(keySchema(idx.PK, idx.SK))
+		if idx.Local {
+			kind, idxs, exp = "local", lsis, keySchemaString(keySchema(s.PK, idx.SK))
+		}
+
+		act, ok := idxs[idx.Name]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s index '%s' doesn't exist", kind, idx.Name))
+		case act != exp:
+			diffs = append(diffs, fmt.Sprintf("%s index '%s' key is %s, expected %s", kind, idx.Name, act, exp))
+		}
+	}
+
+	if len(diffs) > 0 {
+		return &SchemaMismatchError{Table: s.Table, Diffs: diffs}
+	}
+
+	return nil
+}
+
+// keySchemaString formats a key schema for comparison and error messages
+func keySchemaString(ks []*dynamodb.KeySchemaElement) string {
+	var parts []string
+	for _, k := range ks {
+		parts = append(parts, aws.StringValue(k.AttributeName)+"("+aws.StringValue(k.KeyType)+")")
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// EnsureTable makes sure that the table for the item type exists. It creates the table if it
+// doesn't exist. Otherwise, also when it was created concurrently, it checks the table against
+// the schema of the item and returns a SchemaMismatchError if they differ. It then waits for the
+// table and its global indexes to become active.
+func EnsureTable(ctx context.Context, ddb TableDynamo, table string, item Item) error {
+	s, err := NewSchema(table, item)
+	if err != nil {
+		return fmt.Errorf("failed to derive schema: %w", err)
+	}
+
+	desc, err := describeTable(ctx, ddb, table)
+
+	var rnf *dynamodb.ResourceNotFoundException
+	if errors.As(err, &rnf) {
+		var out *dynamodb.CreateTableOutput
+		out, err = ddb.CreateTableWithContext(ctx, s.CreateTableInput())
+
+		var riu *dynamodb.ResourceInUseException
+		switch {
+		case errors.As(err, &riu):
+			desc, err = describeTable(ctx, ddb, table)
+		case err != nil:
+			return fmt.Errorf("failed to create table: %w", err)
+		default:
+			desc = out.TableDescription
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err = s.Check(desc); err != nil {
+		return err
+	}
+
+	for !tableActive(desc) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(schemaPollInterval):
+		}
+
+		if desc, err = describeTable(ctx, ddb, table); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// describeTable returns the description of a table
+func describeTable(ctx context.Context, ddb TableDynamo, table string) (*dynamodb.TableDescription, error) {
+	out, err := ddb.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe table: %w", err)
+	}
+
+	return out.Table, nil
+}
+
+// tableActive returns whether the table and all of its global indexes are active. Global indexes
+// that are added to an existing table are backfilled before they can be queried.
+func tableActive(desc *dynamodb.TableDescription) bool {
+	if aws.StringValue(desc.TableStatus) != dynamodb.TableStatusActive {
+		return false
+	}
+
+	for _, gsi := range desc.GlobalSecondaryIndexes {
+		if aws.StringValue(gsi.IndexStatus) != dynamodb.IndexStatusActive {
+			return false
+		}
+	}
+
+	return true
+}
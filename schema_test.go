@@ -0,0 +1,161 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/gohandle/ddb/memddb"
+)
+
+type schemaBase struct {
+	Owner []byte `dynamodbav:"owner"`
+}
+
+type schemaItem struct {
+	schemaBase
+	PK      *string   `dynamodbav:"pk"`
+	Created time.Time `dynamodbav:"created,unixtime"`
+	Seq     int       `dynamodbav:"seq,string"`
+	Name    string
+	Tags    []string `dynamodbav:"tags"`
+}
+
+func (schemaItem) Keys() (pk, sk string) { return "pk", "created" }
+
+func (schemaItem) Indexes() []Index {
+	return []Index{
+		{Name: "by-owner", PK: "owner", SK: "seq", Projection: dynamodb.ProjectionTypeKeysOnly},
+		{Name: "by-name", SK: "Name", Local: true},
+	}
+}
+
+type schemaTagsItem struct{ schemaItem }
+
+func (schemaTagsItem) Keys() (pk, sk string) { return "pk", "tags" }
+
+type schemaNoKeyItem struct{ Name string }
+
+func (schemaNoKeyItem) Keys() (pk, sk string) { return "pk", "" }
+
+type schemaLocalItem struct{ schemaItem }
+
+func (schemaLocalItem) Indexes() []Index { return []Index{{Name: "by-pk", Local: true}} }
+
+// creatingTableDynamo reports a table as missing on the first describe, as if another process
+// creates it concurrently, and reports its global indexes as creating for the first 'creating'
+// describes
+type creatingTableDynamo struct {
+	TableDynamo
+	missing   bool
+	creating  int
+	describes int
+}
+
+func (cddb *creatingTableDynamo) DescribeTableWithContext(
+	ctx aws.Context,
+	in *dynamodb.DescribeTableInput,
+	opts ...request.Option,
+) (*dynamodb.DescribeTableOutput, error) {
+	cddb.describes++
+	if cddb.missing && cddb.describes == 1 {
+		return nil, &dynamodb.ResourceNotFoundException{}
+	}
+
+	out, err := cddb.TableDynamo.DescribeTableWithContext(ctx, in, opts...)
+	if err != nil || cddb.describes > cddb.creating {
+		return out, err
+	}
+
+	desc := *out.Table
+	desc.GlobalSecondaryIndexes = nil
+	for _, gsi := range out.Table.GlobalSecondaryIndexes {
+		gd := *gsi
+		gd.IndexStatus = aws.String(dynamodb.IndexStatusCreating)
+		desc.GlobalSecondaryIndexes = append(desc.GlobalSecondaryIndexes, &gd)
+	}
+
+	return &dynamodb.DescribeTableOutput{Table: &desc}, nil
+}
+
+func TestNewSchema(t *testing.T) {
+	s, err := NewSchema("tbl", &schemaItem{})
+	if err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if exp := map[string]string{"pk": "S", "created": "N", "owner": "B", "seq": "S", "Name": "S"}; !reflect.DeepEqual(s.Attributes, exp) {
+		t.Fatalf("got: %v", s.Attributes)
+	}
+
+	in := s.CreateTableInput()
+	if err = in.Validate(); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if len(in.AttributeDefinitions) != 5 || aws.StringValue(in.AttributeDefinitions[0].AttributeName) != "Name" ||
+		keySchemaString(in.GlobalSecondaryIndexes[0].KeySchema) != "[owner(HASH),seq(RANGE)]" ||
+		aws.StringValue(in.GlobalSecondaryIndexes[0].Projection.ProjectionType) != "KEYS_ONLY" ||
+		keySchemaString(in.LocalSecondaryIndexes[0].KeySchema) != "[pk(HASH),Name(RANGE)]" {
+		t.Fatalf("got: %v", in)
+	}
+
+	if _, err = NewSchema("tbl", schemaTagsItem{}); err == nil || !strings.Contains(err.Error(), "must be a string") {
+		t.Fatalf("got: %v", err)
+	}
+
+	if _, err = NewSchema("tbl", table1Entity{}.Item()); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if _, err = NewSchema("tbl", schemaNoKeyItem{}); err == nil || !strings.Contains(err.Error(), "not a field") {
+		t.Fatalf("got: %v", err)
+	}
+
+	if _, err = NewSchema("tbl", schemaLocalItem{}); err == nil || !strings.Contains(err.Error(), "must have a sort key") {
+		t.Fatalf("got: %v", err)
+	}
+}
+
+func TestEnsureTable(t *testing.T) {
+	ctx, mddb := context.Background(), memddb.New()
+	if err := EnsureTable(ctx, mddb, "tbl", table2Item{}); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	out, err := mddb.DescribeTableWithContext(ctx, &dynamodb.DescribeTableInput{TableName: aws.String("tbl")})
+	if err != nil || len(out.Table.GlobalSecondaryIndexes) != 1 {
+		t.Fatalf("got: %v %v", out, err)
+	}
+
+	if err = EnsureTable(ctx, mddb, "tbl", table2Item{}); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	var sme *SchemaMismatchError
+	if err = EnsureTable(ctx, mddb, "tbl", &schemaItem{}); !errors.As(err, &sme) || len(sme.Diffs) != 7 {
+		t.Fatalf("got: %v", err)
+	}
+
+	interval := schemaPollInterval
+	schemaPollInterval = time.Millisecond
+	defer func() { schemaPollInterval = interval }()
+
+	t.Run("created concurrently", func(t *testing.T) {
+		cddb := &creatingTableDynamo{TableDynamo: mddb, missing: true, creating: 3}
+		if err := EnsureTable(ctx, cddb, "tbl", table2Item{}); err != nil || cddb.describes != 4 {
+			t.Fatalf("got: %v %v", err, cddb.describes)
+		}
+
+		cddb = &creatingTableDynamo{TableDynamo: mddb, missing: true}
+		if err := EnsureTable(ctx, cddb, "tbl", &schemaItem{}); !errors.As(err, &sme) {
+			t.Fatalf("got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package ddb
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+// Sequence hands out monotonically increasing values, starting at 1, that are counted in a number
+// attribute of a counter item. A sequence is safe for concurrent use.
+type Sequence struct {
+	table string
+	key   Itemizer
+	attr  string
+	block int64
+
+	mu         sync.Mutex
+	next, last int64
+}
+
+// NewSequence inits a sequence that counts in attribute 'attr' of the item with 'key' in 'table'.
+// The item is created when the first value is handed out.
+func NewSequence(table string, key Itemizer, attr string) *Sequence {
+	return &Sequence{table: table, key: key, attr: attr, block: 1}
+}
+
+// Block makes the sequence reserve n values at a time, which are then handed out without a
+// roundtrip to DynamoDB. Values remain unique, but sequences that share a counter no longer hand
+// them out in increasing order relative to each other. Unused values of a block are skipped.
+func (s *Sequence) Block(n int64) *Sequence {
+	s.block = n
+	return s
+}
+
+// Next returns the next value of the sequence
+func (s *Sequence) Next(ctx context.Context, ddb Dynamo) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next == 0 || s.next > s.last {
+		first, err := s.Reserve(ctx, ddb, s.block)
+		if err != nil {
+			return 0, err
+		}
+
+		s.next, s.last = first, first+s.block-1
+	}
+
+	s.next++
+	return s.next - 1, nil
+}
+
+// Reserve reserves n consecutive values, regardless of the block size, and returns the first
+func (s *Sequence) Reserve(ctx context.Context, ddb Dynamo, n int64) (first int64, err error) {
+	if n < 1 {
+		return 0, fmt.Errorf("invalid nr of values to reserve: %d", n)
+	}
+
+	var upd dynamodb.Update
+	upd.SetTableName(s.table)
+	r, err := Update(expression.NewBuilder().
+		WithUpdate(expression.Add(expression.Name(s.attr), expression.Value(n))), upd, s.key).
+		ReturnValues(dynamodb.ReturnValueUpdatedNew).
+		Run(ctx, ddb)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reserve values: %w", err)
+	}
+
+	last, err := s.value(r)
+	if err != nil {
+		return 0, err
+	}
+
+	return last - n + 1, nil
+}
+
+// Increment adds an update that increments the counter to the write and returns the value that
+// the counter has once the write succeeded. The current value is read first and the update is
+// conditional on the counter not being changed in the meantime. If it was, the write fails with a
+// ConditionFailedError and should be retried with a new value.
+func (s *Sequence) Increment(ctx context.Context, ddb Dynamo, tx *Writer) (int64, error) {
+	var get dynamodb.Get
+	get.SetTableName(s.table)
+	r, err := Get(expression.Builder{}, get, s.key).Run(WithConsistentRead(ctx, true), ddb)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read counter: %w", err)
+	}
+
+	cur, err := s.value(r)
+	if err != nil {
+		return 0, err
+	}
+
+	cond := expression.Name(s.attr).Equal(expression.Value(cur))
+	if cur == 0 {
+		cond = expression.AttributeNotExists(expression.Name(s.attr)).Or(cond)
+	}
+
+	var upd dynamodb.Update
+	upd.SetTableName(s.table)
+	tx.Update(expression.NewBuilder().
+		WithCondition(cond).
+		WithUpdate(expression.Set(expression.Name(s.attr), expression.Value(cur+1))), upd, s.key)
+
+	return cur + 1, nil
+}
+
+// value returns the counter from the item in a result, zero if there is no item or counter
+func (s *Sequence) value(r Result) (int64, error) {
+	if !r.Next() {
+		return 0, r.Err()
+	}
+
+	cr, ok := r.(currentResult)
+	if !ok {
+		return 0, fmt.Errorf("result of type %T doesn't expose its items", r)
+	}
+
+	av := cr.current()[s.attr]
+	if av == nil || av.N == nil {
+		return 0, nil
+	}
+
+	v, err := strconv.ParseInt(*av.N, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("counter '%s' is not an integer: %w", s.attr, err)
+	}
+
+	return v, nil
+}
@@ -0,0 +1,106 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/gohandle/ddb/internal/ddbtest"
+)
+
+func TestSequence(t *testing.T) {
+	ctx, tbl := context.Background(), table1(t.Name())
+	mddb := ddbtest.MemDB(t, tbl.createInput())
+
+	counter := &table1Entity{ID: 0}
+	seq1 := NewSequence(string(tbl), counter, "seq")
+	for i := int64(1); i <= 3; i++ {
+		if v, err := seq1.Next(ctx, mddb); err != nil || v != i {
+			t.Fatalf("got: %v %v", v, err)
+		}
+	}
+
+	t.Run("block", func(t *testing.T) {
+		seq2 := NewSequence(string(tbl), counter, "seq").Block(10)
+		for i := int64(4); i <= 6; i++ {
+			if v, err := seq2.Next(ctx, mddb); err != nil || v != i {
+				t.Fatalf("got: %v %v", v, err)
+			}
+		}
+
+		if v, err := seq1.Next(ctx, mddb); err != nil || v != 14 {
+			t.Fatalf("got: %v %v", v, err)
+		}
+
+		if v, err := seq2.Reserve(ctx, mddb, 5); err != nil || v != 15 {
+			t.Fatalf("got: %v %v", v, err)
+		}
+
+		if _, err := seq2.Reserve(ctx, mddb, 0); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+
+	t.Run("increment in write", func(t *testing.T) {
+		tx := NewWriter().Put(tbl.simplePut1(&table1Entity{ID: 100, Name: "order"}))
+		v, err := seq1.Increment(ctx, mddb, tx)
+		if err != nil || v != 20 {
+			t.Fatalf("got: %v %v", v, err)
+		}
+
+		stale := NewWriter()
+		if _, err = seq1.Increment(ctx, mddb, stale); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		if _, err = tx.Run(ctx, mddb); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		var cfe *ConditionFailedError
+		if _, err = stale.Run(ctx, mddb); !errors.As(err, &cfe) {
+			t.Fatalf("got: %v", err)
+		}
+
+		if v, err := seq1.Next(ctx, mddb); err != nil || v != 21 {
+			t.Fatalf("got: %v %v", v, err)
+		}
+	})
+
+	t.Run("new counter", func(t *testing.T) {
+		v, err := NewSequence(string(tbl), &table1Entity{ID: 1}, "seq").Increment(ctx, mddb, NewWriter())
+		if err != nil || v != 1 {
+			t.Fatalf("got: %v %v", v, err)
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		seq := NewSequence(string(tbl), &table1Entity{ID: 2}, "seq").Block(3)
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		seen := map[int64]bool{}
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := seq.Next(ctx, mddb)
+				if err != nil {
+					t.Errorf("got: %v", err)
+				}
+
+				mu.Lock()
+				defer mu.Unlock()
+				seen[v] = true
+			}()
+		}
+
+		wg.Wait()
+		for i := int64(1); i <= 20; i++ {
+			if !seen[i] {
+				t.Fatalf("missing %d, got: %v", i, seen)
+			}
+		}
+	})
+}
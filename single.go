@@ -2,13 +2,26 @@ package ddb
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 )
 
-func writeSingle(ctx context.Context, ddb Dynamo, wi *dynamodb.TransactWriteItem) (r Result, err error) {
+func writeSingle(
+	ctx context.Context,
+	ddb Dynamo,
+	wi *dynamodb.TransactWriteItem,
+	rv *string,
+	rcc *string,
+) (r Result, err error) {
 	var attr map[string]*dynamodb.AttributeValue
+	defer func() {
+		var ccfe *dynamodb.ConditionalCheckFailedException
+		if errors.As(err, &ccfe) {
+			err = &ConditionFailedError{Op: 0, Err: err}
+		}
+	}()
 
 	switch {
 	case wi.Put != nil:
@@ -18,6 +31,8 @@ func writeSingle(ctx context.Context, ddb Dynamo, wi *dynamodb.TransactWriteItem
 			ConditionExpression:       wi.Put.ConditionExpression,
 			ExpressionAttributeNames:  wi.Put.ExpressionAttributeNames,
 			ExpressionAttributeValues: wi.Put.ExpressionAttributeValues,
+			ReturnValues:              rv,
+			ReturnConsumedCapacity:    rcc,
 		}
 
 		var out *dynamodb.PutItemOutput
@@ -25,6 +40,7 @@ func writeSingle(ctx context.Context, ddb Dynamo, wi *dynamodb.TransactWriteItem
 			return nil, fmt.Errorf("failed to put item %v: %w", in, err)
 		}
 
+		addCapacity(ctx, true, out.ConsumedCapacity)
 		attr = out.Attributes
 	case wi.Delete != nil:
 		var out *dynamodb.DeleteItemOutput
@@ -34,10 +50,13 @@ func writeSingle(ctx context.Context, ddb Dynamo, wi *dynamodb.TransactWriteItem
 			ConditionExpression:       wi.Delete.ConditionExpression,
 			ExpressionAttributeNames:  wi.Delete.ExpressionAttributeNames,
 			ExpressionAttributeValues: wi.Delete.ExpressionAttributeValues,
+			ReturnValues:              rv,
+			ReturnConsumedCapacity:    rcc,
 		}); err != nil {
 			return nil, fmt.Errorf("failed to delete item: %w", err)
 		}
 
+		addCapacity(ctx, true, out.ConsumedCapacity)
 		attr = out.Attributes
 	case wi.Update != nil:
 		var out *dynamodb.UpdateItemOutput
@@ -48,10 +67,13 @@ func writeSingle(ctx context.Context, ddb Dynamo, wi *dynamodb.TransactWriteItem
 			ConditionExpression:       wi.Update.ConditionExpression,
 			ExpressionAttributeNames:  wi.Update.ExpressionAttributeNames,
 			ExpressionAttributeValues: wi.Update.ExpressionAttributeValues,
+			ReturnValues:              rv,
+			ReturnConsumedCapacity:    rcc,
 		}); err != nil {
 			return nil, fmt.Errorf("failed to update item: %w", err)
 		}
 
+		addCapacity(ctx, true, out.ConsumedCapacity)
 		attr = out.Attributes
 	default:
 		return nil, fmt.Errorf("unsupported single operation: %v", wi)
@@ -64,17 +86,27 @@ func writeSingle(ctx context.Context, ddb Dynamo, wi *dynamodb.TransactWriteItem
 	return newResult(attr), nil
 }
 
-func readSingle(ctx context.Context, ddb Dynamo, ri *dynamodb.TransactGetItem) (r Result, err error) {
+func readSingle(
+	ctx context.Context,
+	ddb Dynamo,
+	ri *dynamodb.TransactGetItem,
+	rcc *string,
+	cr *bool,
+) (r Result, err error) {
 	var out *dynamodb.GetItemOutput
 	if out, err = ddb.GetItemWithContext(ctx, &dynamodb.GetItemInput{
 		TableName:                ri.Get.TableName,
 		Key:                      ri.Get.Key,
 		ProjectionExpression:     ri.Get.ProjectionExpression,
 		ExpressionAttributeNames: ri.Get.ExpressionAttributeNames,
+		ConsistentRead:           cr,
+		ReturnConsumedCapacity:   rcc,
 	}); err != nil {
 		return nil, fmt.Errorf("failed to get item: %w", err)
 	}
 
+	addCapacity(ctx, false, out.ConsumedCapacity)
+
 	if out.Item == nil {
 		return emptyResult{}, nil
 	}
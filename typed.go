@@ -0,0 +1,85 @@
+package ddb
+
+import (
+	"context"
+	"iter"
+)
+
+// Entity constrains a type parameter to pointers of entities that implement the Itemizer and
+// Deitemizer interface. It allows generic functions to allocate the entities they return.
+type Entity[E any] interface {
+	*E
+	Itemizer
+	Deitemizer
+}
+
+// GetOne runs the reader and returns its first item as an entity. It returns ErrNotFound if the
+// item doesn't exist.
+func GetOne[E any, T Entity[E]](ctx context.Context, ddb Dynamo, r *Reader) (T, error) {
+	res, err := r.Run(ctx, ddb)
+	if err != nil {
+		return nil, err
+	}
+
+	for ent, err := range All[E, T](res) {
+		return ent, err
+	}
+
+	return nil, ErrNotFound
+}
+
+// QueryAll runs the query and returns all the items, across all pages, as entities
+func QueryAll[E any, T Entity[E]](ctx context.Context, ddb Dynamo, q *Querier) ([]T, error) {
+	res, err := q.Run(ctx, ddb)
+	if err != nil {
+		return nil, err
+	}
+
+	return collect[E, T](res)
+}
+
+// ScanAll runs the scan and returns all the items, across all pages, as entities
+func ScanAll[E any, T Entity[E]](ctx context.Context, ddb Dynamo, s *Scanner) ([]T, error) {
+	res, err := s.Run(ctx, ddb)
+	if err != nil {
+		return nil, err
+	}
+
+	return collect[E, T](res)
+}
+
+// All returns an iterator that scans each item of the result into a new entity. When scanning
+// fails, or the result reports an error, the error is yielded and the iteration stops. It
+// consumes the result, which cannot be scanned again afterwards.
+func All[E any, T Entity[E]](r Result) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for r.Next() {
+			ent := T(new(E))
+			if err := r.Scan(ent); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(ent, nil) {
+				return
+			}
+		}
+
+		if err := r.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// collect returns all entities of the result
+func collect[E any, T Entity[E]](r Result) (ents []T, err error) {
+	for ent, err := range All[E, T](r) {
+		if err != nil {
+			return nil, err
+		}
+
+		ents = append(ents, ent)
+	}
+
+	return
+}
@@ -0,0 +1,85 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/gohandle/ddb/internal/ddbtest"
+)
+
+// failingResult is a result with one item that fails after it was returned
+type failingResult struct {
+	Result
+	err error
+}
+
+func (r *failingResult) Err() error { return r.err }
+
+func TestTyped(t *testing.T) {
+	ctx, tbl := context.Background(), table2(t.Name())
+	mddb := ddbtest.MemDB(t, tbl.createInput())
+
+	w := NewWriter(EnableBatchWrites())
+	for i := 0; i < 5; i++ {
+		w.Put(tbl.Put1(&table2Entity{ID: i, Kind: i % 2}))
+	}
+
+	if _, err := w.Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	ent, err := GetOne[table2Entity](ctx, mddb, Get(tbl.Get1(3)))
+	if err != nil || ent == nil || ent.ID != 3 {
+		t.Fatalf("got: %v %v", ent, err)
+	}
+
+	if ent, err = GetOne[table2Entity](ctx, mddb, Get(tbl.Get1(10))); !errors.Is(err, ErrNotFound) || ent != nil {
+		t.Fatalf("got: %v %v", ent, err)
+	}
+
+	ents, err := QueryAll[table2Entity](ctx, mddb, Query(tbl.ByKind(0)))
+	if err != nil || len(ents) != 3 {
+		t.Fatalf("got: %v %v", ents, err)
+	}
+
+	ents, err = ScanAll[table2Entity](ctx, mddb, Scan(e.NewBuilder(), dynamodb.ScanInput{
+		TableName: aws.String(string(tbl)), Limit: aws.Int64(2),
+	}))
+	if err != nil || len(ents) != 5 {
+		t.Fatalf("got: %v %v", ents, err)
+	}
+
+	var ids []int
+	for _, ent := range ents {
+		ids = append(ids, ent.ID)
+	}
+
+	if sort.Ints(ids); ids[0] != 0 || ids[4] != 4 {
+		t.Fatalf("got: %v", ids)
+	}
+
+	t.Run("iterator error", func(t *testing.T) {
+		experr := errors.New("boom")
+		var n int
+		var act error
+		for ent, err := range All[table2Entity](&failingResult{newResult(nil), experr}) {
+			if err != nil {
+				act = err
+				continue
+			}
+			if ent == nil {
+				t.Fatalf("expected entity")
+			}
+			n++
+		}
+
+		if n != 1 || !errors.Is(act, experr) {
+			t.Fatalf("got: %d %v", n, act)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package ddb
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 
@@ -8,6 +9,14 @@ import (
 	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
 )
 
+var (
+	// ErrNotFound is returned by UnmarshalOne and GetOne when the result has no item
+	ErrNotFound = errors.New("item not found")
+
+	// ErrMultipleItems is returned by UnmarshalOne when the result has more than one item
+	ErrMultipleItems = errors.New("more than one item")
+)
+
 // Copied from aws-sdk-go, because Encoder{} does not have EncodeMap()
 func MarshalMap(in interface{}, enableEmptyCollections bool) (map[string]*dynamodb.AttributeValue, error) {
 	encoder := dynamodbattribute.NewEncoder(func(e *dynamodbattribute.Encoder) {
@@ -63,5 +72,33 @@ func UnmarshalAll(r Result, v interface{}) (err error) {
 		return
 	}
 
+	// Len can count more items than were returned, for example when the item budget is spent
+	vv.Set(vv.Slice(0, pos))
 	return
 }
+
+// UnmarshalOne scans the only item of 'r' into 'v'. It returns ErrNotFound if the result has no
+// item, for example when a get didn't find it or a write didn't ask for return values, and
+// ErrMultipleItems if it has more than one. In the latter case 'v' holds the first item.
+func UnmarshalOne(r Result, v interface {
+	Itemizer
+	Deitemizer
+}) (err error) {
+	if !r.Next() {
+		if err = r.Err(); err != nil {
+			return
+		}
+
+		return ErrNotFound
+	}
+
+	if err = r.Scan(v); err != nil {
+		return
+	}
+
+	if r.Next() {
+		return ErrMultipleItems
+	}
+
+	return r.Err()
+}
@@ -0,0 +1,53 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/gohandle/ddb/internal/ddbtest"
+)
+
+func TestUnmarshalOne(t *testing.T) {
+	ctx, tbl := context.Background(), table2(t.Name())
+	mddb := ddbtest.MemDB(t, tbl.createInput())
+
+	if _, err := NewWriter().
+		Put(tbl.Put1(&table2Entity{ID: 1, Kind: 1})).
+		Put(tbl.Put1(&table2Entity{ID: 2, Kind: 1})).
+		Put(tbl.Put1(&table2Entity{ID: 3, Kind: 2})).
+		Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	// the writes come last, they change the kind of item 1
+	for _, c := range []struct {
+		name  string
+		run   func() (Result, error)
+		expID int
+		exp   error
+	}{
+		{"get", func() (Result, error) { return Get(tbl.Get1(2)).Run(ctx, mddb) }, 2, nil},
+		{"get missing", func() (Result, error) { return Get(tbl.Get1(9)).Run(ctx, mddb) }, 0, ErrNotFound},
+		{"query one", func() (Result, error) { return Query(tbl.ByKind(2)).Run(ctx, mddb) }, 3, nil},
+		{"query many", func() (Result, error) { return Query(tbl.ByKind(1)).Run(ctx, mddb) }, 1, ErrMultipleItems},
+		{"query none", func() (Result, error) { return Query(tbl.ByKind(5)).Run(ctx, mddb) }, 0, ErrNotFound},
+		{"write", func() (Result, error) { return Update(tbl.Upd1(1)).Run(ctx, mddb) }, 0, ErrNotFound},
+		{"write values", func() (Result, error) {
+			return Update(tbl.Upd1(1)).ReturnValues(dynamodb.ReturnValueAllNew).Run(ctx, mddb)
+		}, 1, nil},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			r, err := c.run()
+			if err != nil {
+				t.Fatalf("got: %v", err)
+			}
+
+			var ent table2Entity
+			if err = UnmarshalOne(r, &ent); !errors.Is(err, c.exp) || ent.ID != c.expID {
+				t.Fatalf("got: %v %v", ent, err)
+			}
+		})
+	}
+}
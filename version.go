@@ -0,0 +1,166 @@
+package ddb
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// ErrVersionConflict is matched by the VersionConflictError that is returned when a versioned
+// item was changed by someone else since it was read.
+var ErrVersionConflict = errors.New("version conflict")
+
+// Versioned can be implemented by items to opt in to optimistic locking. Version returns the
+// name of the version attribute and the version the item had when it was read, zero for new
+// items. Puts and updates of versioned items are conditional on the stored item not existing or
+// still having that version, and increment the version when they succeed.
+type Versioned interface {
+	Version() (attr string, v int64)
+}
+
+// VersionConflictError is returned when the write of a versioned item failed because the stored
+// item has another version. Op is the index of the operation in the order it was added to the
+// writer and Key the key of the item. It wraps the ConditionFailedError.
+type VersionConflictError struct {
+	Op      int
+	Key     map[string]*dynamodb.AttributeValue
+	Version int64
+	Err     error
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict on operation %d, item %s is no longer at version %d: %v",
+		e.Op, keyString(e.Key), e.Version, e.Err)
+}
+
+func (e *VersionConflictError) Unwrap() error { return e.Err }
+
+// Is makes the error match ErrVersionConflict
+func (e *VersionConflictError) Is(target error) bool { return target == ErrVersionConflict }
+
+// placeholders of the version condition, these don't collide with those of the expression builder
+const (
+	versionAttrName = "#ddbver"
+	versionPKName   = "#ddbpk"
+	versionValue    = ":ddbver"
+	versionNext     = ":ddbnext"
+)
+
+// version describes the version condition of an operation
+type version struct {
+	attr     string
+	v        int64
+	userCond bool
+}
+
+// versionOf returns the version of an item, or nil if it isn't versioned
+func versionOf(item Item) *version {
+	vi, ok := item.(Versioned)
+	if !ok {
+		return nil
+	}
+
+	attr, v := vi.Version()
+	return &version{attr: attr, v: v}
+}
+
+// addCondition adds the version condition to the condition, names and values of an operation
+func (ver *version) addCondition(
+	pk string,
+	cond *string,
+	names map[string]*string,
+	values map[string]*dynamodb.AttributeValue,
+) (*string, map[string]*string, map[string]*dynamodb.AttributeValue) {
+	if names == nil {
+		names = map[string]*string{}
+	}
+	if values == nil {
+		values = map[string]*dynamodb.AttributeValue{}
+	}
+
+	names[versionAttrName], names[versionPKName] = aws.String(ver.attr), aws.String(pk)
+	values[versionValue] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(ver.v, 10))}
+
+	vc := fmt.Sprintf("(attribute_not_exists(%s) OR %s = %s)", versionPKName, versionAttrName, versionValue)
+	if cond != nil {
+		ver.userCond = true
+		vc = fmt.Sprintf("(%s) AND %s", *cond, vc)
+	}
+
+	return aws.String(vc), names, values
+}
+
+// next returns the attribute value of the version that is written
+func (ver *version) next() *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(ver.v+1, 10))}
+}
+
+// addIncrement adds setting the next version to an update expression as build by the
+// expression builder, which puts each clause on its own line.
+func (ver *version) addIncrement(
+	upd *string,
+	values map[string]*dynamodb.AttributeValue,
+) *string {
+	values[versionNext] = ver.next()
+
+	set := fmt.Sprintf("SET %s = %s", versionAttrName, versionNext)
+	if upd == nil {
+		return aws.String(set)
+	}
+
+	lines := strings.Split(*upd, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "SET ") {
+			lines[i] = set + ", " + strings.TrimPrefix(line, "SET ")
+			return aws.String(strings.Join(lines, "\n"))
+		}
+	}
+
+	return aws.String(set + "\n" + *upd)
+}
+
+// conflict returns whether a failed condition was caused by the version. If the operation has
+// a condition of its own this is only known when the stored item was returned.
+func (ver *version) conflict(item map[string]*dynamodb.AttributeValue) bool {
+	if item != nil {
+		av := item[ver.attr]
+		return av == nil || aws.StringValue(av.N) != strconv.FormatInt(ver.v, 10)
+	}
+
+	return !ver.userCond
+}
+
+// versionOf returns the version of an item that is written by the next operation, or nil if it
+// isn't versioned. The version is remembered to report conflicts.
+func (tx *Writer) versionOf(item Item) *version {
+	ver := versionOf(item)
+	if ver == nil {
+		return nil
+	}
+
+	if tx.versions == nil {
+		tx.versions = map[int]*version{}
+	}
+
+	tx.versions[len(tx.writes)] = ver
+	return ver
+}
+
+// versionErr turns a failed condition of a versioned operation into a VersionConflictError
+func (tx *Writer) versionErr(err error) error {
+	var cfe *ConditionFailedError
+	if !errors.As(err, &cfe) {
+		return err
+	}
+
+	ver, ok := tx.versions[cfe.Op]
+	if !ok || !ver.conflict(cfe.Item) {
+		return err
+	}
+
+	return &VersionConflictError{Op: cfe.Op, Key: tx.keys[cfe.Op], Version: ver.v, Err: err}
+}
@@ -0,0 +1,142 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+	"github.com/gohandle/ddb/internal/ddbtest"
+)
+
+type versionedItem struct {
+	PK   string `dynamodbav:"pk"`
+	Name string `dynamodbav:"name"`
+	Ver  int64  `dynamodbav:"version"`
+}
+
+func (versionedItem) Keys() (pk, sk string) { return "pk", "" }
+
+func (it versionedItem) Version() (string, int64) { return "version", it.Ver }
+
+type versionedDoc struct {
+	ID      string
+	Name    string
+	Version int64
+}
+
+func (d versionedDoc) Item() Item {
+	return &versionedItem{PK: d.ID, Name: d.Name, Ver: d.Version}
+}
+
+func (d *versionedDoc) FromItem(it Item) error {
+	vi := it.(*versionedItem)
+	d.ID, d.Name, d.Version = vi.PK, vi.Name, vi.Ver
+	return nil
+}
+
+func TestVersioned(t *testing.T) {
+	ctx, tbl := context.Background(), t.Name()
+	mddb := ddbtest.MemDB(t, mustSchema(tbl, versionedItem{}).CreateTableInput())
+
+	put := func(d *versionedDoc) (b e.Builder, p dynamodb.Put, it Itemizer) {
+		p.SetTableName(tbl)
+		return b, p, d
+	}
+
+	rename := func(d *versionedDoc, name string) (b e.Builder, u dynamodb.Update, it Itemizer) {
+		u.SetTableName(tbl)
+		return b.WithUpdate(e.Set(e.Name("name"), e.Value(name))), u, d
+	}
+
+	get := func(id string) (d versionedDoc) {
+		var g dynamodb.Get
+		g.SetTableName(tbl)
+		r, err := Get(e.NewBuilder(), g, &versionedDoc{ID: id}).Run(ctx, mddb)
+		if err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		if err = UnmarshalOne(r, &d); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+		return
+	}
+
+	if _, err := Put(put(&versionedDoc{ID: "a", Name: "a"})).Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if d := get("a"); d.Version != 1 {
+		t.Fatalf("got: %v", d)
+	}
+
+	_, err := Put(put(&versionedDoc{ID: "a", Name: "b"})).Run(ctx, mddb)
+	var vce *VersionConflictError
+	if !errors.Is(err, ErrVersionConflict) || !errors.As(err, &vce) ||
+		aws.StringValue(vce.Key["pk"].S) != "a" || vce.Version != 0 {
+		t.Fatalf("got: %v", err)
+	}
+
+	if _, err = Put(put(&versionedDoc{ID: "a", Name: "b", Version: 1})).Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if _, err = Update(rename(&versionedDoc{ID: "a", Version: 2}, "c")).Run(ctx, mddb); err != nil {
+		t.Fatalf("got: %v", err)
+	}
+
+	if d := get("a"); d.Version != 3 || d.Name != "c" {
+		t.Fatalf("got: %v", d)
+	}
+
+	if _, err = Update(rename(&versionedDoc{ID: "a", Version: 2}, "d")).Run(ctx, mddb); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("got: %v", err)
+	}
+
+	t.Run("transaction", func(t *testing.T) {
+		_, err := NewWriter().
+			Put(put(&versionedDoc{ID: "b", Name: "b"})).
+			Update(rename(&versionedDoc{ID: "a", Version: 1}, "e")).
+			Run(ctx, mddb)
+
+		var vce *VersionConflictError
+		var cfe *ConditionFailedError
+		if !errors.As(err, &vce) || vce.Op != 1 || aws.StringValue(vce.Key["pk"].S) != "a" ||
+			!errors.As(err, &cfe) {
+			t.Fatalf("got: %v", err)
+		}
+
+		if _, err = NewWriter().
+			Put(put(&versionedDoc{ID: "b", Name: "b"})).
+			Update(rename(&versionedDoc{ID: "a", Version: 3}, "e")).
+			Run(ctx, mddb); err != nil {
+			t.Fatalf("got: %v", err)
+		}
+
+		if d := get("a"); d.Version != 4 || d.Name != "e" {
+			t.Fatalf("got: %v", d)
+		}
+	})
+
+	t.Run("own condition", func(t *testing.T) {
+		b, p, it := put(&versionedDoc{ID: "a", Name: "f", Version: 4})
+		_, err := Put(b.WithCondition(e.Name("name").Equal(e.Value("x"))), p, it).Run(ctx, mddb)
+
+		var cfe *ConditionFailedError
+		if !errors.As(err, &cfe) || errors.Is(err, ErrVersionConflict) {
+			t.Fatalf("got: %v", err)
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		if _, err := NewWriter(EnableBatchWrites()).
+			Put(put(&versionedDoc{ID: "c"})).
+			Put(put(&versionedDoc{ID: "d"})).
+			Run(ctx, mddb); err == nil {
+			t.Fatalf("expected error")
+		}
+	})
+}
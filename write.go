@@ -2,17 +2,27 @@ package ddb
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/dynamodb"
 	"github.com/aws/aws-sdk-go/service/dynamodb/expression"
 )
 
 // Writer represents one or more DynamoDB write operations
 type Writer struct {
-	writes []*dynamodb.TransactWriteItem
-	err    error
-	opts   Options
+	writes   []*dynamodb.TransactWriteItem
+	keys     []map[string]*dynamodb.AttributeValue
+	rv       *string
+	token    *string
+	err      error
+	opts     Options
+	versions map[int]*version
+	events   []int
 }
 
 // NewWriter inits a new write
@@ -44,15 +54,34 @@ func Check(eb expression.Builder, o dynamodb.ConditionCheck, key Itemizer) *Writ
 
 // Put will add a put operation to the write
 func (tx *Writer) Put(eb expression.Builder, put dynamodb.Put, item Itemizer) *Writer {
+	var k Item
 	expr, ok := expression.Expression{}, false
-	if expr, put.Item, _, ok = tx.prepArgs(eb, item); !ok {
+	if expr, put.Item, k, ok = tx.prepArgs(eb, item); !ok {
+		return tx
+	}
+
+	if tx.opts.enableBatchWrites && expr.Condition() != nil {
+		tx.err = fmt.Errorf("conditions are not supported in batch writes")
 		return tx
 	}
 
+	pk, sk := k.Keys()
 	put.ConditionExpression = expr.Condition()
 	put.ExpressionAttributeNames = expr.Names()
 	put.ExpressionAttributeValues = expr.Values()
+	if ver := tx.versionOf(k); ver != nil {
+		if tx.opts.enableBatchWrites {
+			tx.err = fmt.Errorf("versioned items are not supported in batch writes")
+			return tx
+		}
+
+		put.ConditionExpression, put.ExpressionAttributeNames, put.ExpressionAttributeValues = ver.addCondition(
+			pk, put.ConditionExpression, put.ExpressionAttributeNames, put.ExpressionAttributeValues)
+		put.Item[ver.attr] = ver.next()
+	}
+
 	tx.writes = append(tx.writes, &dynamodb.TransactWriteItem{Put: &put})
+	tx.keys = append(tx.keys, mapFilter(put.Item, pk, sk))
 	return tx
 }
 
@@ -64,13 +93,25 @@ func (tx *Writer) Update(eb expression.Builder, upd dynamodb.Update, key Itemize
 		return tx
 	}
 
+	if tx.opts.enableBatchWrites {
+		tx.err = fmt.Errorf("update operations are not supported in batch writes")
+		return tx
+	}
+
 	pk, sk := k.Keys()
 	upd.Key = mapFilter(upd.Key, pk, sk)
 	upd.ConditionExpression = expr.Condition()
 	upd.UpdateExpression = expr.Update()
 	upd.ExpressionAttributeNames = expr.Names()
 	upd.ExpressionAttributeValues = expr.Values()
+	if ver := tx.versionOf(k); ver != nil {
+		upd.ConditionExpression, upd.ExpressionAttributeNames, upd.ExpressionAttributeValues = ver.addCondition(
+			pk, upd.ConditionExpression, upd.ExpressionAttributeNames, upd.ExpressionAttributeValues)
+		upd.UpdateExpression = ver.addIncrement(upd.UpdateExpression, upd.ExpressionAttributeValues)
+	}
+
 	tx.writes = append(tx.writes, &dynamodb.TransactWriteItem{Update: &upd})
+	tx.keys = append(tx.keys, upd.Key)
 	return tx
 }
 
@@ -82,12 +123,18 @@ func (tx *Writer) Delete(eb expression.Builder, del dynamodb.Delete, key Itemize
 		return tx
 	}
 
+	if tx.opts.enableBatchWrites && expr.Condition() != nil {
+		tx.err = fmt.Errorf("conditions are not supported in batch writes")
+		return tx
+	}
+
 	pk, sk := k.Keys()
 	del.Key = mapFilter(del.Key, pk, sk)
 	del.ConditionExpression = expr.Condition()
 	del.ExpressionAttributeNames = expr.Names()
 	del.ExpressionAttributeValues = expr.Values()
 	tx.writes = append(tx.writes, &dynamodb.TransactWriteItem{Delete: &del})
+	tx.keys = append(tx.keys, del.Key)
 	return tx
 }
 
@@ -99,12 +146,35 @@ func (tx *Writer) Check(eb expression.Builder, chk dynamodb.ConditionCheck, key
 		return tx
 	}
 
+	if tx.opts.enableBatchWrites {
+		tx.err = fmt.Errorf("check operations are not supported in batch writes")
+		return tx
+	}
+
 	pk, sk := k.Keys()
 	chk.Key = mapFilter(chk.Key, pk, sk)
 	chk.ConditionExpression = expr.Condition()
 	chk.ExpressionAttributeNames = expr.Names()
 	chk.ExpressionAttributeValues = expr.Values()
 	tx.writes = append(tx.writes, &dynamodb.TransactWriteItem{ConditionCheck: &chk})
+	tx.keys = append(tx.keys, chk.Key)
+	return tx
+}
+
+// ReturnValues sets what attributes are returned when the write consists of a single Put, Update
+// or Delete operation: ALL_OLD, ALL_NEW, UPDATED_OLD or UPDATED_NEW. The attributes are returned
+// as the Result of Run. Transactions don't return attributes, Run returns an error when the write
+// isn't run as a single operation. Each operation may set the ReturnValuesOnConditionCheckFailure
+// to have the failing item returned in a ConditionFailedError instead.
+func (tx *Writer) ReturnValues(rv string) *Writer {
+	tx.rv = &rv
+	return tx
+}
+
+// ClientRequestToken sets the idempotency token that is send with the transaction. A write with
+// a token is always run as a transaction, even if it consists of a single operation.
+func (tx *Writer) ClientRequestToken(tok string) *Writer {
+	tx.token = &tok
 	return tx
 }
 
@@ -114,21 +184,150 @@ func (tx *Writer) Run(ctx context.Context, ddb Dynamo) (r Result, err error) {
 		return nil, tx.err
 	}
 
-	// if only one write, and it is not a condition check downgrade to non-transaction
-	if len(tx.writes) == 1 && tx.writes[0].ConditionCheck == nil {
-		return writeSingle(ctx, ddb, tx.writes[0])
+	if err = tx.deriveEventIDs(); err != nil {
+		return nil, err
+	}
+
+	// if only one write, and it is not a condition check downgrade to non-transaction. Unless it
+	// asks for the item on condition failure or has a token, this is only supported by transactions.
+	if len(tx.writes) == 1 &&
+		tx.writes[0].ConditionCheck == nil &&
+		tx.token == nil &&
+		!returnsOnFailure(tx.writes[0]) {
+		r, err = writeSingle(ctx, ddb, tx.writes[0], tx.rv, tx.opts.returnConsumedCapacity())
+		if tx.alreadyEmitted(err) {
+			return emptyResult{}, nil
+		}
+
+		return r, tx.versionErr(err)
+	}
+
+	if tx.rv != nil {
+		return nil, fmt.Errorf("return values are only supported for a single put, update or delete " +
+			"without a token or ReturnValuesOnConditionCheckFailure")
 	}
 
-	if _, err = ddb.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
-		// @TODO generate and set ClientRequestToken
-		TransactItems: tx.writes,
-	}); err != nil {
-		return nil, fmt.Errorf("failed to transact: %w", err)
+	if tx.opts.enableBatchWrites {
+		return tx.runBatch(ctx, ddb)
+	}
+
+	if tx.opts.enableNonAtomicChunking {
+		return tx.runChunked(ctx, ddb)
+	}
+
+	if err = checkWriteLimits(tx.writes); err != nil {
+		return nil, err
+	}
+
+	if err = tx.transact(ctx, ddb, tx.writes, 0); err != nil && !tx.alreadyEmitted(err) {
+		return nil, err
 	}
 
 	return emptyResult{}, nil
 }
 
+// transact runs the writes as a single transaction, offs is the index of the first write in
+// the writer and is used to report which operation failed.
+func (tx *Writer) transact(
+	ctx context.Context,
+	ddb Dynamo,
+	writes []*dynamodb.TransactWriteItem,
+	offs int,
+) (err error) {
+	in := &dynamodb.TransactWriteItemsInput{
+		TransactItems:          writes,
+		ReturnConsumedCapacity: tx.opts.returnConsumedCapacity(),
+	}
+
+	if in.ClientRequestToken, err = tx.requestToken(writes, offs); err != nil {
+		return err
+	}
+
+	var out *dynamodb.TransactWriteItemsOutput
+	if out, err = ddb.TransactWriteItemsWithContext(ctx, in); err != nil {
+		err = fmt.Errorf("failed to transact: %w", err)
+
+		var ipme *dynamodb.IdempotentParameterMismatchException
+		if errors.As(err, &ipme) {
+			return &IdempotencyMismatchError{Token: aws.StringValue(in.ClientRequestToken), Err: err}
+		}
+
+		var tce *dynamodb.TransactionCanceledException
+		if !errors.As(err, &tce) {
+			return err
+		}
+
+		tcerr := &TxCanceledError{Err: err}
+		for i, reason := range tce.CancellationReasons {
+			if offs+i >= len(tx.keys) {
+				break
+			}
+
+			tcerr.Reasons = append(tcerr.Reasons, TxCancelReason{
+				Op:      offs + i,
+				Code:    aws.StringValue(reason.Code),
+				Message: aws.StringValue(reason.Message),
+				Key:     tx.keys[offs+i],
+				Item:    reason.Item,
+			})
+		}
+
+		// a failed condition is reported as such, while the cancellation details remain
+		// available by unwrapping it.
+		for _, reason := range tcerr.Reasons {
+			if reason.Code == TxReasonConditionalCheckFailed {
+				return tx.versionErr(&ConditionFailedError{Op: reason.Op, Item: reason.Item, Err: tcerr})
+			}
+		}
+
+		return tcerr
+	}
+
+	addCapacity(ctx, true, out.ConsumedCapacity...)
+	return nil
+}
+
+// requestToken returns the ClientRequestToken for the transaction of 'writes'. If the write was
+// split in chunks each chunk gets its own token that is derived from the explicit one.
+func (tx *Writer) requestToken(writes []*dynamodb.TransactWriteItem, offs int) (*string, error) {
+	switch {
+	case tx.token != nil && len(writes) == len(tx.writes):
+		return tx.token, nil
+	case tx.token != nil:
+		return aws.String(hashToken([]byte(fmt.Sprintf("%s/%d", *tx.token, offs)))), nil
+	case tx.opts.enableIdempotencyTokens:
+		data, err := json.Marshal(writes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal transaction for token: %w", err)
+		}
+
+		return aws.String(hashToken(data)), nil
+	default:
+		return nil, nil
+	}
+}
+
+// hashToken returns a token that is derived from 'data' and fits DynamoDB's length limit of 36.
+func hashToken(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// returnsOnFailure returns whether the write asks for the item when its condition fails
+func returnsOnFailure(wi *dynamodb.TransactWriteItem) bool {
+	var rv *string
+	switch {
+	case wi.Put != nil:
+		rv = wi.Put.ReturnValuesOnConditionCheckFailure
+	case wi.Update != nil:
+		rv = wi.Update.ReturnValuesOnConditionCheckFailure
+	case wi.Delete != nil:
+		rv = wi.Delete.ReturnValuesOnConditionCheckFailure
+	}
+
+	return rv != nil && *rv != dynamodb.ReturnValuesOnConditionCheckFailureNone
+}
+
 // prepArgs will do checks for what is provided for a write operation
 func (tx *Writer) prepArgs(
 	eb expression.Builder,
@@ -0,0 +1,146 @@
+package ddb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	e "github.com/aws/aws-sdk-go/service/dynamodb/expression"
+)
+
+func TestBatchWriterRefusals(t *testing.T) {
+	tbl := table2(t.Name())
+
+	w := NewWriter(EnableBatchWrites()).Update(tbl.Upd1(1))
+	if w.err == nil || !strings.Contains(w.err.Error(), "update operations are not supported") {
+		t.Fatalf("got: %v", w.err)
+	}
+
+	w = NewWriter(EnableBatchWrites()).Check(tbl.Chc1(1, 1))
+	if w.err == nil || !strings.Contains(w.err.Error(), "check operations are not supported") {
+		t.Fatalf("got: %v", w.err)
+	}
+
+	b, op, it := tbl.Put1(&table2Entity{ID: 1})
+	w = NewWriter(EnableBatchWrites()).Put(b.WithCondition(e.AttributeNotExists(e.Name("pk"))), op, it)
+	if w.err == nil || !strings.Contains(w.err.Error(), "conditions are not supported") {
+		t.Fatalf("got: %v", w.err)
+	}
+
+	w = NewWriter(EnableBatchWrites()).Put(tbl.Put1(&table2Entity{ID: 1})).Delete(tbl.Del1(2))
+	if w.err != nil {
+		t.Fatalf("got: %v", w.err)
+	}
+}
+
+func TestReturnValuesRefusals(t *testing.T) {
+	ctx, tbl := context.Background(), table2(t.Name())
+	for i, w := range []*Writer{
+		NewWriter().Put(tbl.Put1(&table2Entity{ID: 1})).Delete(tbl.Del1(2)),
+		NewWriter().Put(tbl.Put1(&table2Entity{ID: 1})).ClientRequestToken("foo"),
+		NewWriter().Check(tbl.Chc1(1, 1)),
+		NewWriter(EnableBatchWrites()).Put(tbl.Put1(&table2Entity{ID: 1})).Delete(tbl.Del1(2)),
+	} {
+		_, err := w.ReturnValues(dynamodb.ReturnValueAllOld).Run(ctx, nil)
+		if err == nil || !strings.Contains(err.Error(), "return values are only supported") {
+			t.Fatalf("%d: got: %v", i, err)
+		}
+	}
+}
+
+func TestWriterLimits(t *testing.T) {
+	tbl := table2(t.Name())
+
+	w := NewWriter()
+	for i := 0; i < 101; i++ {
+		w.Put(tbl.Put1(&table2Entity{ID: i}))
+	}
+
+	var lerr *TxLimitError
+	if _, err := w.Run(context.Background(), nil); !errors.As(err, &lerr) || lerr.Limit != TxLimitOperations {
+		t.Fatalf("got: %v", err)
+	}
+
+	if act, err := chunkWrites(w.writes); err != nil || !reflect.DeepEqual(act, []int{100, 1}) {
+		t.Fatalf("got: %v %v", act, err)
+	}
+
+	w = NewWriter()
+	for i := 0; i < 12; i++ {
+		w.Put(tbl.Put1(&table2Entity{ID: i}))
+		w.writes[i].Put.Item["data"] = &dynamodb.AttributeValue{B: make([]byte, 350*1024)}
+	}
+
+	if _, err := w.Run(context.Background(), nil); !errors.As(err, &lerr) || lerr.Limit != TxLimitSize {
+		t.Fatalf("got: %v", err)
+	}
+
+	if act, err := chunkWrites(w.writes); err != nil || !reflect.DeepEqual(act, []int{11, 1}) {
+		t.Fatalf("got: %v %v", act, err)
+	}
+
+	w.writes[0].Put.Item["data"] = &dynamodb.AttributeValue{B: make([]byte, 5*1024*1024)}
+	if _, err := chunkWrites(w.writes); !errors.As(err, &lerr) || lerr.Limit != TxLimitSize {
+		t.Fatalf("got: %v", err)
+	}
+}
+
+func TestTxChunkError(t *testing.T) {
+	err := &TxChunkError{Chunks: []int{100, 100, 5}, Failed: 2, Err: errors.New("foo")}
+	if act := err.Committed(); act != 200 {
+		t.Fatalf("got: %v", act)
+	}
+
+	if act := err.Error(); act != "failed to transact chunk 3 of 3, 200 operation(s) were committed: foo" {
+		t.Fatalf("got: %v", act)
+	}
+}
+
+func TestTxCanceledError(t *testing.T) {
+	err := &TxCanceledError{Err: errors.New("foo"), Reasons: []TxCancelReason{
+		{Op: 0, Code: TxReasonNone},
+		{Op: 1, Code: TxReasonTransactionConflict},
+	}}
+
+	if act := err.Error(); act != "transaction canceled [1:TransactionConflict]: foo" {
+		t.Fatalf("got: %v", act)
+	}
+
+	if !err.Has(TxReasonTransactionConflict) || err.Has(TxReasonConditionalCheckFailed) {
+		t.Fatalf("got: %v", err)
+	}
+}
+
+func TestRequestToken(t *testing.T) {
+	tbl := table2(t.Name())
+
+	w1 := NewWriter(EnableIdempotencyTokens()).Put(tbl.Put1(&table2Entity{ID: 1})).Put(tbl.Put1(&table2Entity{ID: 2}))
+	w2 := NewWriter(EnableIdempotencyTokens()).Put(tbl.Put1(&table2Entity{ID: 1})).Put(tbl.Put1(&table2Entity{ID: 2}))
+	w3 := NewWriter(EnableIdempotencyTokens()).Put(tbl.Put1(&table2Entity{ID: 1})).Put(tbl.Put1(&table2Entity{ID: 3}))
+
+	tok1, _ := w1.requestToken(w1.writes, 0)
+	tok2, _ := w2.requestToken(w2.writes, 0)
+	tok3, _ := w3.requestToken(w3.writes, 0)
+	if tok1 == nil || len(*tok1) != 32 || *tok1 != *tok2 || *tok1 == *tok3 {
+		t.Fatalf("got: %v %v %v", tok1, tok2, tok3)
+	}
+
+	w4 := NewWriter().Put(tbl.Put1(&table2Entity{ID: 1})).Put(tbl.Put1(&table2Entity{ID: 2}))
+	if tok4, _ := w4.requestToken(w4.writes, 0); tok4 != nil {
+		t.Fatalf("got: %v", *tok4)
+	}
+
+	w4.ClientRequestToken("my-token")
+	if tok4, _ := w4.requestToken(w4.writes, 0); tok4 == nil || *tok4 != "my-token" {
+		t.Fatalf("got: %v", tok4)
+	}
+
+	tok5, _ := w4.requestToken(w4.writes[1:], 1)
+	tok6, _ := w4.requestToken(w4.writes[:1], 0)
+	if *tok5 == *tok6 || len(*tok5) != 32 {
+		t.Fatalf("got: %v %v", *tok5, *tok6)
+	}
+}